@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"cache/core"
+	"cache/transaction"
+	"cache/transaction/binaryEvent"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// hasKey reports whether the event or any change of its transaction is of the key
+func hasKey(e core.Event, key string) bool {
+	if e.Key == key && e.Type != core.EventTxn {
+		return true
+	}
+
+	for _, op := range e.Batch {
+		if op.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func dump(args []string) error {
+	f := newFlags("dump")
+	format := f.String("format", "text", "text or json, json is the same as records of json log")
+	key := f.String("key", "", "print only events of the key")
+	from := f.Uint64("from", 0, "print only events with ID from it")
+	to := f.Uint64("to", math.MaxUint64, "print only events with ID up to it")
+
+	path, keys, err := f.parse(args)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	var write func(file string, offset int64, e core.Event) error
+
+	switch *format {
+	case "text":
+		write = func(file string, offset int64, e core.Event) error {
+			return writeText(w, file, offset, e)
+		}
+	case "json":
+		codec, err := transaction.NewCodec(binaryEvent.Header{Version: binaryEvent.Version, Format: byte(transaction.JSONFormat)}, nil)
+		if err != nil {
+			return err
+		}
+
+		write = func(_ string, _ int64, e core.Event) error {
+			return codec.Write(w, e)
+		}
+	default:
+		return fmt.Errorf("unknown format %q, it should be text or json", *format)
+	}
+
+	return scanLog(path, keys, func(file string, offset int64, e core.Event) error {
+		if e.ID < *from || e.ID > *to || (*key != "" && !hasKey(e, *key)) {
+			return nil
+		}
+
+		return write(file, offset, e)
+	})
+}
+
+// writeText writes the event as one line with its place in the log,
+// changes of transaction are written on the next lines
+func writeText(w io.Writer, file string, offset int64, e core.Event) error {
+	line := fmt.Sprintf("%s:%d id=%d", file, offset, e.ID)
+
+	if e.Time != 0 {
+		line += " time=" + time.Unix(0, e.Time).UTC().Format(time.RFC3339Nano)
+	}
+
+	line += " " + fields(e)
+
+	for _, op := range e.Batch {
+		line += "\n  " + fields(op)
+	}
+
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+func fields(e core.Event) string {
+	parts := []string{"type=" + core.EventName(e.Type)}
+
+	if e.Key != "" {
+		parts = append(parts, fmt.Sprintf("key=%q", e.Key))
+	}
+	if core.HasField(e.Type) {
+		parts = append(parts, fmt.Sprintf("field=%q", e.Field))
+	}
+	if len(e.Value) != 0 {
+		parts = append(parts, fmt.Sprintf("value=%q", e.Value))
+	}
+	if e.ContentType != "" {
+		parts = append(parts, fmt.Sprintf("content_type=%q", e.ContentType))
+	}
+	if e.Deadline != 0 {
+		parts = append(parts, "deadline="+time.Unix(0, e.Deadline).UTC().Format(time.RFC3339Nano))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"cache/core"
+	"cache/transaction"
+	"cache/transaction/binaryEvent"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// cache-log inspects and repairs the log offline, the server should be stopped
+// before truncate and convert, because they rewrite files of the log
+const usage = `usage: cache-log <command> [flags] <logs_path>
+
+commands:
+  dump      print events as text or JSON lines
+  verify    check every record and report the first bad offset
+  truncate  drop the record and everything after it
+  convert   rewrite the log by other format, compression or key
+  stats     count events by type and find keys overwritten the most
+
+run cache-log <command> -h for flags of the command`
+
+var commands = map[string]func(args []string) error{
+	"dump":     dump,
+	"verify":   verify,
+	"truncate": truncate,
+	"convert":  convert,
+	"stats":    stats,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n%s\n", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err := command(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// logFlags are flags of every command, path of the log is the only argument
+type logFlags struct {
+	*flag.FlagSet
+	keyFile *string
+}
+
+func newFlags(name string) logFlags {
+	set := flag.NewFlagSet(name, flag.ExitOnError)
+
+	return logFlags{
+		FlagSet: set,
+		keyFile: set.String("key_file", "", "file of keys of encrypted log, "+transaction.KeysEnv+" is used if it is empty"),
+	}
+}
+
+// parse returns path of the log and its keys
+func (f logFlags) parse(args []string) (string, *binaryEvent.Keyring, error) {
+	if err := f.Parse(args); err != nil {
+		return "", nil, err
+	}
+
+	if f.NArg() != 1 {
+		return "", nil, errors.New("path of the log is required")
+	}
+
+	keys, err := transaction.LoadKeyring(*f.keyFile)
+	return f.Arg(0), keys, err
+}
+
+// scanLog calls f for every record of the files of the log in order,
+// it stops at the first bad record
+func scanLog(path string, keys *binaryEvent.Keyring, f func(file string, offset int64, e core.Event) error) error {
+	files, err := transaction.LogFiles(path)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		_, offset, err := transaction.ScanFile(file, keys, func(offset int64, e core.Event) error {
+			return f(file, offset, e)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: bad record at offset %d: %w", file, offset, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"cache/core"
+	"cache/transaction"
+	"cache/transaction/binaryEvent"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testKey = "key:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+
+func put(id uint64, key string, value string) core.Event {
+	return core.Event{ID: id, Type: core.EventPut, Key: key, Value: []byte(value)}
+}
+
+// fixtureLog writes the log of six events: the first segment has events 1-4,
+// events 5 and 6 have their own segments
+func fixtureLog(t *testing.T) string {
+	t.Helper()
+
+	t.Setenv(transaction.KeysEnv, "")
+
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	sessions := []struct {
+		opts   transaction.Options
+		events []core.Event
+	}{
+		{transaction.Options{Bandwidth: 4}, []core.Event{
+			put(1, "a", "1"),
+			put(2, "b", "2"),
+			put(3, "a", "3"),
+			{ID: 4, Type: core.EventTxn, Batch: []core.Event{{Type: core.EventDelete, Key: "b"}, put(0, "c", "4")}},
+		}},
+		//the active segment is rolled over before every event
+		{transaction.Options{Bandwidth: 4, SegmentSize: 1}, []core.Event{
+			put(5, "a", "5"),
+			{ID: 6, Type: core.EventDelete, Key: "c"},
+		}},
+	}
+
+	for _, session := range sessions {
+		tl, err := transaction.NewLogger(path, session.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tl.Start()
+
+		for _, e := range session.events {
+			if err = <-tl.WriteEvent(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err = tl.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return path
+}
+
+// run runs the command and returns what it printed
+func run(t *testing.T, command func(args []string) error, args ...string) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+
+	err = command(args)
+
+	os.Stdout = stdout
+	_ = w.Close()
+
+	return <-out, err
+}
+
+var idPattern = regexp.MustCompile(` id=(\d+) `)
+
+// dumpedIDs returns IDs of events printed by dump as text
+func dumpedIDs(t *testing.T, args ...string) []uint64 {
+	t.Helper()
+
+	out, err := run(t, dump, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []uint64
+
+	for _, match := range idPattern.FindAllStringSubmatch(out, -1) {
+		id, _ := strconv.ParseUint(match[1], 10, 64)
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func TestDump(t *testing.T) {
+	path := fixtureLog(t)
+
+	tests := []struct {
+		name string
+		args []string
+		want []uint64
+	}{
+		{"all", nil, []uint64{1, 2, 3, 4, 5, 6}},
+		{"key", []string{"-key", "a"}, []uint64{1, 3, 5}},
+		{"key in transaction", []string{"-key", "b"}, []uint64{2, 4}},
+		{"range", []string{"-from", "3", "-to", "5"}, []uint64{3, 4, 5}},
+		{"key and range", []string{"-key", "c", "-from", "5"}, []uint64{6}},
+	}
+
+	for _, test := range tests {
+		if got := dumpedIDs(t, append(test.args, path)...); !slices.Equal(got, test.want) {
+			t.Fatalf("%s: got IDs %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	out, err := run(t, dump, "-format", "json", "-key", "c", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`{"id":4,"type":"txn","batch":[{"type":"delete","key":"b"},{"type":"put","key":"c","value":"4"}]}`,
+		`{"id":6,"type":"delete","key":"c"}`,
+	}
+	if got := strings.Split(strings.TrimSpace(out), "\n"); !slices.Equal(got, want) {
+		t.Fatalf("got json %q, want %q", got, want)
+	}
+
+	if _, err = run(t, dump, "-format", "xml", path); err == nil {
+		t.Fatal("got no error of unknown format")
+	}
+}
+
+func TestStats(t *testing.T) {
+	out, err := run(t, stats, "-top", "2", fixtureLog(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `records: 6, IDs: 1-6, keys: 3
+events by type (changes of transactions included):
+  delete     2
+  put        5
+  txn        1
+top keys by overwrites:
+  2          "a"
+  1          "b"
+`
+	if out != want {
+		t.Fatalf("got stats\n%s\nwant\n%s", out, want)
+	}
+
+	if out, err = run(t, stats, t.TempDir()); err != nil || out != "log is empty\n" {
+		t.Fatalf("got stats %q (%v) of empty log", out, err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	path := fixtureLog(t)
+
+	if _, err := run(t, truncate, "-id", "4", path); err != nil {
+		t.Fatal(err)
+	}
+	if got := dumpedIDs(t, path); !slices.Equal(got, []uint64{1, 2, 3}) {
+		t.Fatalf("got IDs %v after truncation by ID, want 1-3", got)
+	}
+
+	if _, err := run(t, truncate, "-id", "42", path); err == nil {
+		t.Fatal("got no error of unknown ID")
+	}
+
+	//dump prints offset of the record after its file
+	out, err := run(t, dump, "-key", "a", "-from", "3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, offset, found := strings.Cut(strings.Fields(out)[0], ":")
+	if !found {
+		t.Fatalf("got no offset in %q", out)
+	}
+
+	if _, err = run(t, truncate, "-file", file, "-offset", strconv.Itoa(mustAtoi(t, offset)+1), path); err == nil {
+		t.Fatal("got no error of offset inside the record")
+	}
+
+	if _, err = run(t, truncate, "-file", file, "-offset", offset, path); err != nil {
+		t.Fatal(err)
+	}
+	if got := dumpedIDs(t, path); !slices.Equal(got, []uint64{1, 2}) {
+		t.Fatalf("got IDs %v after truncation at offset, want 1-2", got)
+	}
+
+	//dropped records are kept
+	if _, err = os.Stat(file + ".truncated-" + offset); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustAtoi(t *testing.T, s string) int {
+	t.Helper()
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func TestConvert(t *testing.T) {
+	path := fixtureLog(t)
+
+	keyFile := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(keyFile, []byte(testKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := run(t, convert, "-log_format", "json", "-encrypt", "-key_file", keyFile, path); err == nil {
+		t.Fatal("got no error of encrypted json log")
+	}
+	if _, err := run(t, convert, "-encrypt", path); err == nil {
+		t.Fatal("got no error of encryption without keys")
+	}
+
+	//json lines of the log could be found by grep
+	if _, err := run(t, convert, "-log_format", "json", path); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := os.Open(filepath.Join(path, "00000000000000000001.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+
+	lines := bufio.NewScanner(first)
+	lines.Scan()
+
+	var header map[string]any
+	if err = json.Unmarshal(lines.Bytes(), &header); err != nil || header["format"] != "json" {
+		t.Fatalf("got header %q (%v), want json log", lines.Text(), err)
+	}
+
+	if _, err = run(t, convert, "-log_format", "gob", "-compression", "flate", "-encrypt", "-key_file", keyFile, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := dumpedIDs(t, "-key_file", keyFile, path); !slices.Equal(got, []uint64{1, 2, 3, 4, 5, 6}) {
+		t.Fatalf("got IDs %v after conversion, want 1-6", got)
+	}
+
+	keys, err := binaryEvent.ParseKeyring(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := transaction.LogFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		h, _, err := transaction.ScanFile(file, keys, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Format != byte(transaction.GobFormat) || h.Compression != binaryEvent.Flate || h.KeyID != "key" {
+			t.Fatalf("%s: got header %+v, want encrypted gob with flate", file, h)
+		}
+	}
+
+	if _, err = run(t, dump, path); err == nil {
+		t.Fatal("got no error of encrypted log without keys")
+	}
+}
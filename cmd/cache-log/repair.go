@@ -0,0 +1,139 @@
+package main
+
+import (
+	"cache/core"
+	"cache/transaction"
+	"cache/transaction/binaryEvent"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// errFound stops scan when the record is found
+var errFound = errors.New("record is found")
+
+func verify(args []string) error {
+	path, keys, err := newFlags("verify").parse(args)
+	if err != nil {
+		return err
+	}
+
+	files, err := transaction.LogFiles(path)
+	if err != nil {
+		return err
+	}
+
+	var firstBad error
+
+	for _, file := range files {
+		records := 0
+
+		header, offset, err := transaction.ScanFile(file, keys, func(int64, core.Event) error {
+			records++
+			return nil
+		})
+
+		if err == nil {
+			fmt.Printf("%s: version %d, format %d, %d records, ok\n", file, header.Version, header.Format, records)
+			continue
+		}
+
+		fmt.Printf("%s: %d good records, bad record at offset %d: %v\n", file, records, offset, err)
+
+		if errors.Is(err, binaryEvent.ErrTruncated) && file == files[len(files)-1] {
+			fmt.Println("  torn record at the end of the last segment is truncated on start")
+		}
+
+		if firstBad == nil {
+			firstBad = fmt.Errorf("first bad record is at offset %d of %s", offset, file)
+		}
+	}
+
+	return firstBad
+}
+
+func truncate(args []string) error {
+	f := newFlags("truncate")
+	id := f.Uint64("id", 0, "drop the first record with the ID and everything after it")
+	segment := f.String("file", "", "segment to truncate at -offset, instead of -id")
+	offset := f.Int64("offset", -1, "offset of the record in -file, verify reports offset of bad record")
+
+	path, keys, err := f.parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *segment != "" {
+		if *offset < 0 {
+			return errors.New("offset is required with file")
+		}
+
+		return transaction.Truncate(filepath.Join(path, filepath.Base(*segment)), *offset, keys)
+	}
+
+	if *id == 0 {
+		return errors.New("id or file with offset is required")
+	}
+
+	files, err := transaction.LogFiles(path)
+	if err != nil {
+		return err
+	}
+
+	//the snapshot is the state, not the history, so it is not truncated
+	for _, file := range files {
+		if filepath.Base(file) == "snapshot" {
+			continue
+		}
+
+		_, at, err := transaction.ScanFile(file, keys, func(_ int64, e core.Event) error {
+			if e.ID == *id {
+				return errFound
+			}
+
+			return nil
+		})
+		if errors.Is(err, errFound) {
+			return transaction.Truncate(file, at, keys)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: bad record at offset %d before the ID is found: %w", file, at, err)
+		}
+	}
+
+	return fmt.Errorf("record with ID %d is not found", *id)
+}
+
+func convert(args []string) error {
+	f := newFlags("convert")
+	format := f.String("log_format", "binary", "binary, gob or json")
+	compression := f.String("compression", "none", "none or flate")
+	encrypt := f.Bool("encrypt", false, "encrypt by the active key, otherwise the log is decrypted")
+
+	path, keys, err := f.parse(args)
+	if err != nil {
+		return err
+	}
+
+	h := binaryEvent.Header{}
+
+	formatValue, err := transaction.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	h.Format = byte(formatValue)
+
+	if h.Compression, err = binaryEvent.ParseCompression(*compression); err != nil {
+		return err
+	}
+
+	if *encrypt {
+		if keys == nil {
+			return errors.New("keys are required to encrypt the log")
+		}
+
+		h.KeyID = keys.Active()
+	}
+
+	return transaction.Convert(path, h, keys)
+}
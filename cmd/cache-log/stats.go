@@ -0,0 +1,79 @@
+package main
+
+import (
+	"cache/core"
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+func stats(args []string) error {
+	f := newFlags("stats")
+	top := f.Int("top", 10, "how many keys overwritten the most are printed")
+
+	path, keys, err := f.parse(args)
+	if err != nil {
+		return err
+	}
+
+	records := 0
+	minID, maxID := uint64(math.MaxUint64), uint64(0)
+	types := map[core.EventType]int{}
+	writes := map[string]int{}
+
+	count := func(e core.Event) {
+		types[e.Type]++
+
+		if e.Type != core.EventTxn && e.Type != core.EventClear && e.Type != core.EventSnapshot {
+			writes[e.Key]++
+		}
+	}
+
+	err = scanLog(path, keys, func(_ string, _ int64, e core.Event) error {
+		records++
+		minID, maxID = min(minID, e.ID), max(maxID, e.ID)
+
+		count(e)
+		for _, op := range e.Batch {
+			count(op)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if records == 0 {
+		fmt.Println("log is empty")
+		return nil
+	}
+
+	fmt.Printf("records: %d, IDs: %d-%d, keys: %d\n", records, minID, maxID, len(writes))
+
+	fmt.Println("events by type (changes of transactions included):")
+	for _, t := range slices.Sorted(maps.Keys(types)) {
+		fmt.Printf("  %-10s %d\n", core.EventName(t), types[t])
+	}
+
+	//the first write of the key is not an overwrite
+	overwritten := make([]string, 0, len(writes))
+	for key, n := range writes {
+		if n > 1 {
+			overwritten = append(overwritten, key)
+		}
+	}
+
+	slices.SortFunc(overwritten, func(a, b string) int {
+		return cmp.Or(cmp.Compare(writes[b], writes[a]), cmp.Compare(a, b))
+	})
+
+	fmt.Println("top keys by overwrites:")
+	for _, key := range overwritten[:min(*top, len(overwritten))] {
+		fmt.Printf("  %-10d %q\n", writes[key]-1, key)
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"cache/core"
+	"cache/transaction"
 	"flag"
 	"runtime"
 	"time"
@@ -11,14 +13,60 @@ type Config struct {
 	Port            string
 	LogsPath        string
 	TimeForShutdown time.Duration
+	ReaperInterval  time.Duration
+	MaxKeys         int
+	MaxBytes        int
+	EvictionPolicy  string
+	Shards          int
+	MaxBodySize     int64
+	SnapshotEvery   time.Duration
+	SnapshotLogSize int64
+	RestoreTo       string
+	Corruption      string
+	Migrate         bool
+	SegmentSize     int64
+	SegmentAge      time.Duration
+	RetainSegments  int
+	Durability      string
+	SyncInterval    time.Duration
+	HealthInterval  time.Duration
+	Compression     string
+	KeyFile         string
+	Reencrypt       bool
+	LogFormat       string
+	Leader          string
+	ForwardWrites   bool
 }
 
 func Get() Config {
 	//todo write usage and docs
 	port := flag.String("port", "8080", "")
-	logsPath := flag.String("logs_path", "logs.bin", "")
+	logsPath := flag.String("logs_path", "logs.bin", "directory of the log, log of one file is moved into it")
 	timeForShutdown := flag.Duration("time_for_shutdown", 5*time.Minute, "")
 	bandwidth := flag.Int("bandwidth", 10*runtime.NumCPU(), "")
+	reaperInterval := flag.Duration("reaper_interval", time.Second, "how often expired keys are removed")
+	maxKeys := flag.Int("max_keys", 0, "max number of keys, 0 means unlimited")
+	maxBytes := flag.Int("max_bytes", 0, "max total size of keys and values, 0 means unlimited")
+	evictionPolicy := flag.String("eviction_policy", "lru", "random, lru, lfu or ttl")
+	shards := flag.Int("shards", core.DefaultShards, "number of independently locked partitions of the store")
+	maxBodySize := flag.Int64("max_body_size", 16<<20, "max size of request body in bytes, 0 means unlimited")
+	snapshotEvery := flag.Duration("snapshot_interval", 0, "how often snapshot of the store is taken, 0 means never")
+	snapshotLogSize := flag.Int64("snapshot_log_size", 0, "size of the log in bytes those triggers snapshot, 0 means never")
+	restoreTo := flag.String("restore_to", "", "event ID or RFC 3339 time, store is rolled back to it on start")
+	corruption := flag.String("corruption_policy", "fail", "fail or quarantine, what to do with broken record in the middle of the log")
+	segmentSize := flag.Int64("segment_size", 64<<20, "size of log segment in bytes those rolls it over to the new one, 0 means never")
+	segmentAge := flag.Duration("segment_age", 0, "age of log segment those rolls it over to the new one, 0 means never")
+	retainSegments := flag.Int("retain_segments", 0, "how many log segments covered by snapshot are kept")
+	durability := flag.String("durability", "interval", "none, interval or always, when written events are synced to the disk")
+	syncInterval := flag.Duration("sync_interval", time.Second, "how often the log is synced with interval durability")
+	healthInterval := flag.Duration("health_interval", time.Second, "how often read-only store checks if the log is writable again")
+	compression := flag.String("compression", "none", "none or flate, compression of new log segments and snapshots")
+	logFormat := flag.String("log_format", "binary", "binary, gob or json, format of records of new log segments and snapshots")
+	keyFile := flag.String("key_file", "", "file of keys those encrypt the log, "+transaction.KeysEnv+" is used if it is empty")
+	reencrypt := flag.Bool("reencrypt", false, "rewrite the log encrypted by older keys with the active key and exit")
+	leader := flag.String("leader", "", "URL of the leader, the store becomes its follower if it is set")
+	forwardWrites := flag.Bool("forward_writes", true, "follower forwards changes to the leader, otherwise they are rejected")
+	migrate := flag.Bool("migrate", false, "upgrade the log to the current format and exit, it is also done on start")
 
 	flag.Parse()
 
@@ -27,5 +75,28 @@ func Get() Config {
 		*port,
 		*logsPath,
 		*timeForShutdown,
+		*reaperInterval,
+		*maxKeys,
+		*maxBytes,
+		*evictionPolicy,
+		*shards,
+		*maxBodySize,
+		*snapshotEvery,
+		*snapshotLogSize,
+		*restoreTo,
+		*corruption,
+		*migrate,
+		*segmentSize,
+		*segmentAge,
+		*retainSegments,
+		*durability,
+		*syncInterval,
+		*healthInterval,
+		*compression,
+		*keyFile,
+		*reencrypt,
+		*logFormat,
+		*leader,
+		*forwardWrites,
 	}
 }
@@ -0,0 +1,211 @@
+package core
+
+import (
+	"sync"
+)
+
+// Flusher is implemented by logger those write events in background,
+// Flush acks when every event queued before it is written to the log,
+// so reading the log after the ack gets these events
+type Flusher interface {
+	Flush() <-chan error
+}
+
+// Feed streams every change of the store in order of IDs, history is read
+// from the log first and live changes follow it without gaps or repeats
+type Feed struct {
+	watcher *Watcher
+	events  chan Event
+	err     error
+	done    chan struct{}
+	close   sync.Once
+}
+
+// Changes returns feed of events after the event since, zero means from the
+// beginning: if the log has the snapshot, it is sent first as EventSnapshot with
+// keys of the state in Batch with their versions as IDs. Transaction is sent as one event.
+// ErrorHistoryCompacted is returned if events after since were replaced by the
+// snapshot, so the consumer should start from the beginning. Logger without
+// history feeds only changes made after the call
+func (s *Store) Changes(since uint64, buffer int) (*Feed, error) {
+	w := &Watcher{store: s, all: true, events: make(chan Event, max(buffer, 1))}
+
+	//events logged before the watcher is added are read from the log and later
+	//ones come to the watcher, flush queued after them makes them readable
+	flushed := Logged()
+
+	s.seqMu.Lock()
+	s.addWatcher(w)
+	if flusher, ok := s.tl.(Flusher); ok {
+		flushed = flusher.Flush()
+	}
+	s.seqMu.Unlock()
+
+	if err := <-flushed; err != nil {
+		w.Close()
+		return nil, err
+	}
+
+	events, errs := s.tl.ReadEvents()
+
+	var first Event
+	var ok bool
+
+	//the snapshot is the first event of the log, if it is there
+	select {
+	case first, ok = <-events:
+	case err := <-errs:
+		if err != nil {
+			w.Close()
+			drain(events, errs)
+			return nil, err
+		}
+	}
+
+	if ok && first.Type == EventSnapshot && since != 0 && since < first.ID {
+		w.Close()
+		drain(events, errs)
+		return nil, ErrorHistoryCompacted
+	}
+
+	f := &Feed{watcher: w, events: make(chan Event), done: make(chan struct{})}
+	go f.run(since, first, ok, events, errs)
+
+	return f, nil
+}
+
+// Events is closed when feed is closed or failed
+func (f *Feed) Events() <-chan Event {
+	return f.events
+}
+
+// Err reports why the feed ended after Events is closed, it is nil if feed was
+// closed, ErrorWatcherTooSlow if consumer did not keep up with live changes
+func (f *Feed) Err() error {
+	return f.err
+}
+
+func (f *Feed) Close() {
+	f.close.Do(func() {
+		close(f.done)
+	})
+}
+
+func (f *Feed) run(since uint64, first Event, ok bool, events <-chan Event, errs <-chan error) {
+	defer close(f.events)
+	defer f.watcher.Close()
+
+	sent, snapshotID := since, uint64(0)
+	var snapshot *Event
+
+	//snapshot is sent with its keys in Batch when all of them are read
+	sendSnapshot := func() bool {
+		if snapshot == nil {
+			return true
+		}
+
+		e := *snapshot
+		snapshot = nil
+		sent = max(sent, e.ID)
+
+		return f.send(e)
+	}
+
+	replay := func(e Event) bool {
+		switch {
+		case e.Type == EventSnapshot:
+			snapshotID = e.ID
+			if since == 0 {
+				snapshot = &e
+			}
+
+			sent = max(sent, e.ID)
+			return true
+		case e.ID <= snapshotID:
+			//keys of the snapshot are the state, not changes after since
+			if snapshot != nil {
+				snapshot.Batch = append(snapshot.Batch, e)
+			}
+
+			return true
+		case e.ID <= sent:
+			return true
+		}
+
+		sent = max(sent, e.ID)
+		return sendSnapshot() && f.send(e)
+	}
+
+	if ok && !replay(first) {
+		drain(events, errs)
+		return
+	}
+
+	for events != nil || errs != nil {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+			} else if !replay(e) {
+				drain(events, errs)
+				return
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+			} else if err != nil {
+				f.err = err
+				drain(events, errs)
+				return
+			}
+		}
+	}
+
+	if !sendSnapshot() {
+		return
+	}
+
+	//live events could be read from the log already, they are skipped
+	for {
+		select {
+		case <-f.done:
+			return
+		case e, ok := <-f.watcher.Events():
+			if !ok {
+				f.err = f.watcher.Err()
+				return
+			}
+
+			if e.ID > sent && !f.send(e) {
+				return
+			}
+
+			sent = max(sent, e.ID)
+		}
+	}
+}
+
+func (f *Feed) send(e Event) bool {
+	select {
+	case f.events <- e:
+		return true
+	case <-f.done:
+		return false
+	}
+}
+
+// drain reads the rest of the log, so its reader is not blocked forever
+func drain(events <-chan Event, errs <-chan error) {
+	for events != nil || errs != nil {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+			}
+		case _, ok := <-errs:
+			if !ok {
+				errs = nil
+			}
+		}
+	}
+}
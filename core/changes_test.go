@@ -0,0 +1,107 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChanges(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	first, _ := s.Put("a", []byte("1"))
+	s.Put("b", []byte("2"))
+
+	all, err := s.Changes(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer all.Close()
+
+	resumed, err := s.Changes(first, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+
+	txn := s.Begin()
+	txn.Put("c", []byte("3"))
+	txn.Delete("a")
+
+	id, err := txn.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receive(t, all, EventPut, "a", first)
+	receive(t, all, EventPut, "b", first+1)
+	receive(t, all, EventTxn, "", id)
+
+	//history is followed by live changes without repeats
+	receive(t, resumed, EventPut, "b", first+1)
+	receive(t, resumed, EventTxn, "", id)
+
+	s.Delete("b")
+	receive(t, resumed, EventDelete, "b", id+1)
+}
+
+func TestChangesCompacted(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	first, _ := s.Put("a", []byte("1"))
+	second, _ := s.Put("b", []byte("2"))
+
+	if err := s.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Changes(first, 10); !errors.Is(err, ErrorHistoryCompacted) {
+		t.Fatalf("got error %v, want %v", err, ErrorHistoryCompacted)
+	}
+
+	//the beginning is the snapshot with its keys
+	all, err := s.Changes(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer all.Close()
+
+	select {
+	case e := <-all.Events():
+		if e.Type != EventSnapshot || e.ID != second || len(e.Batch) != 2 {
+			t.Fatalf("got event %d with ID %d and %d keys, want snapshot %d with 2 keys", e.Type, e.ID, len(e.Batch), second)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no snapshot")
+	}
+
+	resumed, err := s.Changes(second, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+
+	third, _ := s.Put("c", []byte("3"))
+	receive(t, all, EventPut, "c", third)
+	receive(t, resumed, EventPut, "c", third)
+}
+
+func TestChangesSlowConsumer(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	slow, err := s.Changes(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slow.Close()
+
+	for range 3 {
+		s.Put("key", []byte("value"))
+	}
+
+	for range slow.Events() {
+	}
+	if !errors.Is(slow.Err(), ErrorWatcherTooSlow) {
+		t.Fatalf("got error %v, want %v", slow.Err(), ErrorWatcherTooSlow)
+	}
+}
@@ -0,0 +1,300 @@
+package core
+
+import (
+	"cache/set"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+var ErrorWrongType = errors.New("operation against a key holding the wrong kind of value")
+var ErrorNoSuchField = errors.New("no such field")
+
+// errNotChanged is returned by mutate when operation does not change
+// the collection, such operations are not logged
+var errNotChanged = errors.New("collection is not changed")
+
+type kind byte
+
+const (
+	kindString kind = iota
+	kindList
+	kindHash
+	kindSet
+	kindZSet
+)
+
+func kindOf(t EventType) kind {
+	switch t {
+	case EventListPushLeft, EventListPushRight, EventListPopLeft, EventListPopRight:
+		return kindList
+	case EventHashSet, EventHashDelete:
+		return kindHash
+	case EventSetAdd, EventSetRemove:
+		return kindSet
+	case EventZSetAdd, EventZSetRemove:
+		return kindZSet
+	default:
+		return kindString
+	}
+}
+
+func isCollectionEvent(t EventType) bool {
+	return kindOf(t) != kindString
+}
+
+func newCollection(k kind) entry {
+	switch k {
+	case kindList:
+		return entry{kind: kindList, list: &deque{}}
+	case kindHash:
+		return entry{kind: kindHash, hash: make(map[string]string)}
+	case kindSet:
+		return entry{kind: kindSet, set: set.New()}
+	default:
+		return entry{kind: kindZSet, zset: newZSet()}
+	}
+}
+
+// count returns number of items in collection
+func (e entry) count() int {
+	switch e.kind {
+	case kindList:
+		return e.list.len
+	case kindHash:
+		return len(e.hash)
+	case kindSet:
+		return e.set.Len()
+	case kindZSet:
+		return len(e.zset.scores)
+	default:
+		return 1
+	}
+}
+
+// sizeAfter returns size of the collection before and after the change described
+// by the event, so the change is checked before it is applied, caller should hold the lock
+func (sh *shard) sizeAfter(e Event, now int64) (int, int) {
+	c, exists := sh.data[e.Key]
+	if !exists || c.expired(now) {
+		c = newCollection(kindOf(e.Type))
+	} else if c.kind != kindOf(e.Type) {
+		return c.size, c.size
+	}
+
+	value := string(e.Value)
+	size := c.size
+
+	switch e.Type {
+	case EventListPushLeft, EventListPushRight:
+		size += len(value)
+	case EventHashSet:
+		if old, ok := c.hash[e.Field]; ok {
+			size -= len(e.Field) + len(old)
+		}
+
+		size += len(e.Field) + len(value)
+	case EventSetAdd:
+		if !c.set.Contains(value) {
+			size += len(value)
+		}
+	case EventZSetAdd:
+		if _, ok := c.zset.scores[e.Field]; !ok {
+			size += len(e.Field) + scoreSize
+		}
+	}
+
+	return c.size, size
+}
+
+// mutate applies change of the collection described by the event, it is used
+// both by operations and by replay, so they always give the same result.
+// Collection is removed when its last item is removed. It returns removed item
+// for pops and previous score for update of member of sorted set,
+// caller should hold the lock
+func (sh *shard) mutate(e Event, now int64) (string, error) {
+	c, exists := sh.data[e.Key]
+	if exists && c.expired(now) {
+		exists = false
+	}
+
+	if !exists {
+		c = newCollection(kindOf(e.Type))
+	} else if c.kind != kindOf(e.Type) {
+		return "", ErrorWrongType
+	}
+
+	var removed string
+	value := string(e.Value)
+
+	switch e.Type {
+	case EventListPushLeft:
+		c.list.pushFront(value)
+		c.size += len(value)
+	case EventListPushRight:
+		c.list.pushBack(value)
+		c.size += len(value)
+	case EventListPopLeft, EventListPopRight:
+		if !exists {
+			return "", ErrorNoSuchKey
+		}
+
+		if e.Type == EventListPopLeft {
+			removed = c.list.popFront()
+		} else {
+			removed = c.list.popBack()
+		}
+
+		c.size -= len(removed)
+	case EventHashSet:
+		if old, ok := c.hash[e.Field]; ok {
+			c.size -= len(e.Field) + len(old)
+		}
+
+		c.hash[e.Field] = value
+		c.size += len(e.Field) + len(value)
+	case EventHashDelete:
+		if !exists {
+			return "", ErrorNoSuchKey
+		}
+
+		old, ok := c.hash[e.Field]
+		if !ok {
+			return "", ErrorNoSuchField
+		}
+
+		delete(c.hash, e.Field)
+		c.size -= len(e.Field) + len(old)
+	case EventSetAdd:
+		if c.set.Contains(value) {
+			return "", errNotChanged
+		}
+
+		c.set.Add(value)
+		c.size += len(value)
+	case EventSetRemove:
+		if !exists {
+			return "", ErrorNoSuchKey
+		}
+		if !c.set.Contains(value) {
+			return "", errNotChanged
+		}
+
+		c.set.Remove(value)
+		c.size -= len(value)
+	case EventZSetAdd:
+		score, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(score) || math.IsInf(score, 0) {
+			return "", ErrorInvalidScore
+		}
+
+		old, ok := c.zset.scores[e.Field]
+		if ok && old == score {
+			return "", errNotChanged
+		}
+
+		if ok {
+			removed = formatScore(old)
+		} else {
+			c.size += len(e.Field) + scoreSize
+		}
+
+		c.zset.add(e.Field, score)
+	case EventZSetRemove:
+		if !exists {
+			return "", ErrorNoSuchKey
+		}
+		if _, ok := c.zset.scores[value]; !ok {
+			return "", errNotChanged
+		}
+
+		c.zset.remove(value)
+		c.size -= len(value) + scoreSize
+	}
+
+	c.version = e.ID
+
+	if c.count() == 0 {
+		sh.remove(e.Key)
+	} else {
+		sh.set(e.Key, c)
+	}
+
+	return removed, nil
+}
+
+// mutate applies the change to the collection and logs it if collection was changed,
+// it returns removed item for pops and number of items left in the collection
+func (s *Store) mutate(e Event) (string, int, error) {
+	sh := s.shard(e.Key)
+
+	sh.Lock()
+	removed, count, ack, err := s.mutateLocked(sh, e)
+	sh.Unlock()
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	return removed, count, s.wait(ack)
+}
+
+// mutateLocked is mutate for callers those already hold the lock of the shard,
+// they wait for the returned ack after the lock is released
+func (s *Store) mutateLocked(sh *shard, e Event) (string, int, <-chan error, error) {
+	if err := s.writable(); err != nil {
+		return "", 0, nil, err
+	}
+
+	now := time.Now().UnixNano()
+
+	//grown collection over the limit would be evicted right after the change is logged
+	if before, after := sh.sizeAfter(e, now); after > before && sh.tooLarge(len(e.Key)+after) {
+		return "", 0, nil, ErrorTooLarge
+	}
+
+	removed, err := sh.mutate(e, now)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	//popped item is not needed for replay, but makes the log readable
+	if e.Type == EventListPopLeft || e.Type == EventListPopRight {
+		e.Value = []byte(removed)
+	}
+
+	version, ack := s.log(e)
+	count := 0
+
+	if c, ok := sh.data[e.Key]; ok {
+		c.version = version
+		sh.data[e.Key] = c
+		count = c.count()
+	}
+
+	sh.evict(s.log)
+
+	return removed, count, ack, nil
+}
+
+// read calls f with the live collection of given kind under read lock
+func (s *Store) read(key string, k kind, f func(c entry)) error {
+	sh := s.shard(key)
+
+	sh.RLock()
+	defer sh.RUnlock()
+
+	c, ok := sh.data[key]
+	if !ok || c.expired(time.Now().UnixNano()) {
+		return ErrorNoSuchKey
+	}
+	if c.kind != k {
+		return ErrorWrongType
+	}
+
+	sh.touch(key)
+	f(c)
+
+	return nil
+}
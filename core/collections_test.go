@@ -0,0 +1,276 @@
+package core
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestDeque(t *testing.T) {
+	d := &deque{}
+	var want []string
+
+	//mixed pushes and pops make ring buffer wrap around and grow
+	for i := 0; i < 100; i++ {
+		item := strconv.Itoa(i)
+
+		switch i % 5 {
+		case 0, 1:
+			d.pushFront(item)
+			want = append([]string{item}, want...)
+		case 2, 3:
+			d.pushBack(item)
+			want = append(want, item)
+		default:
+			if got := d.popFront(); got != want[0] {
+				t.Fatalf("popped %q, want %q", got, want[0])
+			}
+			want = want[1:]
+		}
+	}
+
+	if got := d.slice(0, -1); !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := d.slice(-3, -2); !slices.Equal(got, want[len(want)-3:len(want)-1]) {
+		t.Fatalf("got %v, want %v", got, want[len(want)-3:len(want)-1])
+	}
+	if got := d.slice(5, 2); len(got) != 0 {
+		t.Fatalf("got %v for empty range", got)
+	}
+	if got := d.popBack(); got != want[len(want)-1] {
+		t.Fatalf("popped %q, want %q", got, want[len(want)-1])
+	}
+}
+
+func TestList(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	for i, item := range []string{"b", "c"} {
+		if length, err := s.ListPush("list", ListRight, item); err != nil || length != i+1 {
+			t.Fatalf("got length %d (%v), want %d", length, err, i+1)
+		}
+	}
+	if _, err := s.ListPush("list", ListLeft, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if items, err := s.ListRange("list", 0, -1); err != nil || !slices.Equal(items, []string{"a", "b", "c"}) {
+		t.Fatalf("got %v (%v), want [a b c]", items, err)
+	}
+
+	if item, err := s.ListPop("list", ListRight); err != nil || item != "c" {
+		t.Fatalf("got %q (%v), want c", item, err)
+	}
+	if item, err := s.ListPop("list", ListLeft); err != nil || item != "a" {
+		t.Fatalf("got %q (%v), want a", item, err)
+	}
+	if _, err := s.ListPop("list", ListLeft); err != nil {
+		t.Fatal(err)
+	}
+
+	//list is deleted with its last item
+	if _, err := s.ListPop("list", ListLeft); !errors.Is(err, ErrorNoSuchKey) {
+		t.Fatalf("got error %v, want %v", err, ErrorNoSuchKey)
+	}
+}
+
+func TestHash(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	if err := s.HashSet("hash", "a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.HashSet("hash", "b", "2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := s.HashGet("hash", "a"); err != nil || value != "1" {
+		t.Fatalf("got %q (%v), want 1", value, err)
+	}
+	if _, err := s.HashGet("hash", "c"); !errors.Is(err, ErrorNoSuchField) {
+		t.Fatalf("got error %v, want %v", err, ErrorNoSuchField)
+	}
+	if err := s.HashDelete("hash", "c"); !errors.Is(err, ErrorNoSuchField) {
+		t.Fatalf("got error %v, want %v", err, ErrorNoSuchField)
+	}
+
+	if err := s.HashDelete("hash", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := s.HashGetAll("hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(fields, map[string]string{"b": "2"}) {
+		t.Fatalf("got %v, want map[b:2]", fields)
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	if added, err := s.SetAdd("set", "a"); err != nil || !added {
+		t.Fatalf("got %v (%v), want added", added, err)
+	}
+	if added, err := s.SetAdd("set", "a"); err != nil || added {
+		t.Fatalf("got %v (%v), want not added", added, err)
+	}
+	if _, err := s.SetAdd("set", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	members, err := s.SetMembers("set")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slices.Sort(members)
+	if !slices.Equal(members, []string{"a", "b"}) {
+		t.Fatalf("got %v, want [a b]", members)
+	}
+
+	if member, err := s.SetRandomMember("set"); err != nil || !slices.Contains(members, member) {
+		t.Fatalf("got random member %q (%v)", member, err)
+	}
+
+	if removed, err := s.SetRemove("set", "c"); err != nil || removed {
+		t.Fatalf("got %v (%v), want not removed", removed, err)
+	}
+	if removed, err := s.SetRemove("set", "a"); err != nil || !removed {
+		t.Fatalf("got %v (%v), want removed", removed, err)
+	}
+
+	if exists, err := s.SetIsMember("set", "a"); err != nil || exists {
+		t.Fatalf("got %v (%v), want removed member", exists, err)
+	}
+}
+
+func TestWrongType(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	s.Put("string", []byte("value"))
+	if _, err := s.SetAdd("set", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ListPush("string", ListLeft, "a"); !errors.Is(err, ErrorWrongType) {
+		t.Fatalf("got error %v, want %v", err, ErrorWrongType)
+	}
+	if _, err := s.Get("set"); !errors.Is(err, ErrorWrongType) {
+		t.Fatalf("got error %v, want %v", err, ErrorWrongType)
+	}
+	if _, err := s.Increment("set", 1); !errors.Is(err, ErrorWrongType) {
+		t.Fatalf("got error %v, want %v", err, ErrorWrongType)
+	}
+	if _, err := s.HashGet("set", "a"); !errors.Is(err, ErrorWrongType) {
+		t.Fatalf("got error %v, want %v", err, ErrorWrongType)
+	}
+
+	//put overwrites value of any kind
+	s.Put("set", []byte("value"))
+	checkValue(t, s, "set", "value")
+}
+
+func TestRestoreCollections(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	for _, item := range []string{"a", "b", "c"} {
+		if _, err := s.ListPush("list", ListRight, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.ListPop("list", ListLeft); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.HashSet("hash", "field", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetAdd("set", "member"); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewStore(tl)
+	if err := restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	if items, err := restored.ListRange("list", 0, -1); err != nil || !slices.Equal(items, []string{"b", "c"}) {
+		t.Fatalf("got %v (%v), want [b c]", items, err)
+	}
+	if value, err := restored.HashGet("hash", "field"); err != nil || value != "value" {
+		t.Fatalf("got %q (%v), want value", value, err)
+	}
+	if exists, err := restored.SetIsMember("set", "member"); err != nil || !exists {
+		t.Fatalf("got %v (%v), want member", exists, err)
+	}
+}
+
+func TestCollectionsSize(t *testing.T) {
+	s := NewShardedStore(&memLogger{}, 1).WithEviction(newFifoPolicy, 0, 100)
+
+	if _, err := s.ListPush("list", ListRight, "12345"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.HashSet("hash", "field", "12345"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.HashSet("hash", "field", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	//list(4) + 12345(5) + hash(4) + field(5) + 1(1)
+	if size := s.shards[0].bytes; size != 19 {
+		t.Fatalf("got size %d, want 19", size)
+	}
+
+	if _, err := s.ListPop("list", ListLeft); err != nil {
+		t.Fatal(err)
+	}
+
+	if size := s.shards[0].bytes; size != 10 {
+		t.Fatalf("got size %d, want 10", size)
+	}
+}
+
+func TestCollectionTooLarge(t *testing.T) {
+	tl := &memLogger{}
+	s := NewShardedStore(tl, 1).WithEviction(newFifoPolicy, 0, 12)
+
+	//list(4) + 1234(4) + 1234(4) is the whole limit
+	for range 2 {
+		if _, err := s.ListPush("list", ListRight, "1234"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//push over the limit would log it and then evict the whole list
+	if _, err := s.ListPush("list", ListRight, "1"); !errors.Is(err, ErrorTooLarge) {
+		t.Fatalf("got error %v, want %v", err, ErrorTooLarge)
+	}
+	if items, err := s.ListRange("list", 0, -1); err != nil || !slices.Equal(items, []string{"1234", "1234"}) {
+		t.Fatalf("got %v (%v), want [1234 1234]", items, err)
+	}
+	if size := tl.LogSize(); size != 2 {
+		t.Fatalf("got %d events, want 2", size)
+	}
+
+	//shrinking change is allowed
+	if _, err := s.ListPop("list", ListLeft); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.HashSet("hash", "f", "12345678"); !errors.Is(err, ErrorTooLarge) {
+		t.Fatalf("got error %v of hash, want %v", err, ErrorTooLarge)
+	}
+	if _, err := s.SetAdd("set", "1234567890"); !errors.Is(err, ErrorTooLarge) {
+		t.Fatalf("got error %v of set, want %v", err, ErrorTooLarge)
+	}
+	if items, err := s.ListRange("list", 0, -1); err != nil || !slices.Equal(items, []string{"1234"}) {
+		t.Fatalf("got %v (%v), want [1234]", items, err)
+	}
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+var ErrorNotInteger = errors.New("value is not an integer")
+var ErrorOverflow = errors.New("increment or decrement would overflow")
+
+// Increment adds delta to the integer value of the key and returns the result,
+// missing key is treated as zero. Ttl of the key is kept. Result is logged, so
+// replay gives exactly the same value
+func (s *Store) Increment(key string, delta int64) (int64, error) {
+	result, ack, err := s.incrementLocked(key, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	return result, s.wait(ack)
+}
+
+func (s *Store) incrementLocked(key string, delta int64) (int64, <-chan error, error) {
+	if err := s.writable(); err != nil {
+		return 0, nil, err
+	}
+
+	sh := s.shard(key)
+
+	sh.Lock()
+	defer sh.Unlock()
+
+	old, exists := sh.data[key]
+	if exists && old.expired(time.Now().UnixNano()) {
+		old, exists = entry{}, false
+	}
+
+	if exists && old.kind != kindString {
+		return 0, nil, ErrorWrongType
+	}
+
+	var current int64
+
+	if exists {
+		var err error
+		if current, err = strconv.ParseInt(string(old.value), 10, 64); err != nil {
+			return 0, nil, ErrorNotInteger
+		}
+	}
+
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return 0, nil, ErrorOverflow
+	}
+
+	var ack <-chan error
+
+	e := entry{value: strconv.AppendInt(nil, current+delta, 10), deadline: old.deadline}
+	e.version, ack = s.log(Event{Type: EventIncrement, Key: key, Value: e.value, Deadline: e.deadline})
+	sh.set(key, e)
+	sh.evict(s.log)
+
+	return current + delta, ack, nil
+}
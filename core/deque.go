@@ -0,0 +1,89 @@
+package core
+
+// deque is a growable ring buffer with O(1) push and pop on both ends
+// and O(1) access by index, it backs list values
+type deque struct {
+	items []string
+	head  int
+	len   int
+}
+
+func (d *deque) grow() {
+	items := make([]string, max(2*len(d.items), 8))
+
+	for i := 0; i < d.len; i++ {
+		items[i] = d.at(i)
+	}
+
+	d.items = items
+	d.head = 0
+}
+
+func (d *deque) index(i int) int {
+	return (d.head + i) % len(d.items)
+}
+
+func (d *deque) at(i int) string {
+	return d.items[d.index(i)]
+}
+
+func (d *deque) pushFront(item string) {
+	if d.len == len(d.items) {
+		d.grow()
+	}
+
+	d.head = (d.head - 1 + len(d.items)) % len(d.items)
+	d.items[d.head] = item
+	d.len++
+}
+
+func (d *deque) pushBack(item string) {
+	if d.len == len(d.items) {
+		d.grow()
+	}
+
+	d.items[d.index(d.len)] = item
+	d.len++
+}
+
+func (d *deque) popFront() string {
+	item := d.items[d.head]
+	d.items[d.head] = ""
+	d.head = d.index(1)
+	d.len--
+
+	return item
+}
+
+func (d *deque) popBack() string {
+	i := d.index(d.len - 1)
+	item := d.items[i]
+	d.items[i] = ""
+	d.len--
+
+	return item
+}
+
+// slice returns copy of items from start to stop inclusive, negative indexes
+// are counted from the end like in redis LRANGE
+func (d *deque) slice(start int, stop int) []string {
+	if start < 0 {
+		start = max(d.len+start, 0)
+	}
+	if stop < 0 {
+		stop = d.len + stop
+	}
+
+	stop = min(stop, d.len-1)
+
+	if start > stop {
+		return []string{}
+	}
+
+	items := make([]string, 0, stop-start+1)
+	for i := start; i <= stop; i++ {
+		items = append(items, d.at(i))
+	}
+
+	return items
+}
@@ -1,16 +1,114 @@
 package core
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type EventType = byte
 
 const (
 	EventDelete EventType = iota
 	EventPut
 	EventClear
+	EventPutWithTTL
+	EventExpire
+	EventEvict
+	EventTxn
+	EventIncrement
+	EventListPushLeft
+	EventListPushRight
+	EventListPopLeft
+	EventListPopRight
+	EventHashSet
+	EventHashDelete
+	EventSetAdd
+	EventSetRemove
+	EventZSetAdd
+	EventZSetRemove
+	EventSnapshot
 )
 
+// EventNames are names of event types in text formats of events
+var EventNames = map[EventType]string{
+	EventDelete:        "delete",
+	EventPut:           "put",
+	EventClear:         "clear",
+	EventPutWithTTL:    "put-ttl",
+	EventExpire:        "expire",
+	EventEvict:         "evict",
+	EventTxn:           "txn",
+	EventIncrement:     "incr",
+	EventListPushLeft:  "lpush",
+	EventListPushRight: "rpush",
+	EventListPopLeft:   "lpop",
+	EventListPopRight:  "rpop",
+	EventHashSet:       "hset",
+	EventHashDelete:    "hdel",
+	EventSetAdd:        "sadd",
+	EventSetRemove:     "srem",
+	EventZSetAdd:       "zadd",
+	EventZSetRemove:    "zrem",
+	EventSnapshot:      "snapshot",
+}
+
+// EventName returns name of the type, unknown type is named by its number
+func EventName(t EventType) string {
+	if name, ok := EventNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("type-%d", t)
+}
+
+// ParseEventType returns type of the name given by EventName
+func ParseEventType(name string) (EventType, error) {
+	for t, n := range EventNames {
+		if n == name {
+			return t, nil
+		}
+	}
+
+	if number, ok := strings.CutPrefix(name, "type-"); ok {
+		if t, err := strconv.ParseUint(number, 10, 8); err == nil {
+			return EventType(t), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown event type: %q", name)
+}
+
 type Event struct {
-	ID    uint64
-	Type  EventType
-	Key   string
-	Value string
+	ID uint64
+	//unix time in nanoseconds when event was logged, events of transaction share it
+	Time int64
+	Type EventType
+	Key  string
+	//field of hash or member of sorted set, used only by EventHashSet,
+	//EventHashDelete and EventZSetAdd, score of EventZSetAdd is the value
+	Field string
+	Value []byte
+	//media type of the value, used only by EventPut and EventPutWithTTL
+	ContentType string
+	//unix time in nanoseconds when key expires, zero if key never expires
+	Deadline int64
+	//changes of EventTxn those are applied all together, they share ID of the transaction
+	Batch []Event
+}
+
+// HasDeadline reports whether events of this type carry deadline of the key
+func HasDeadline(t EventType) bool {
+	return t == EventPutWithTTL || t == EventIncrement
+}
+
+// HasContentType reports whether events of this type carry media type of the value
+func HasContentType(t EventType) bool {
+	return t == EventPut || t == EventPutWithTTL
+}
+
+// HasField reports whether events of this type carry field of hash
+// or member of sorted set
+func HasField(t EventType) bool {
+	return t == EventHashSet || t == EventHashDelete || t == EventZSetAdd
 }
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrorTooLarge = errors.New("key and value are bigger than the size limit of the shard")
+var ErrorLimitTooSmall = errors.New("limit should be at least the number of shards")
+
+// EvictionPolicy chooses which key to drop when shard is over its limits.
+// Store calls it only under lock of the shard, so implementations do not need
+// to be safe for concurrent use
+type EvictionPolicy interface {
+	//Add is called when key is put or overwritten, deadline is zero for keys without ttl
+	Add(key string, deadline int64)
+	//Touch is called when key is read
+	Touch(key string)
+	Remove(key string)
+	Clear()
+	//Victim returns next key to evict without removing it, false if policy has no keys
+	Victim() (string, bool)
+}
+
+func entrySize(key string, e entry) int {
+	if e.kind == kindString {
+		return len(key) + len(e.value)
+	}
+
+	return len(key) + e.size
+}
+
+// WithEviction limits number of keys and total size of keys and values,
+// zero means no limit. Limits are split evenly between shards and every shard
+// evicts its own keys with policy created by newPolicy, so store could start
+// evicting a bit before global limit if keys are distributed unevenly, and
+// key with value should fit the share of one shard. Limits are checked by CheckLimits
+func (s *Store) WithEviction(newPolicy func() EvictionPolicy, maxKeys int, maxBytes int) *Store {
+	for _, sh := range s.shards {
+		sh.policy = newPolicy()
+		sh.maxKeys = perShard(maxKeys, len(s.shards))
+		sh.maxBytes = perShard(maxBytes, len(s.shards))
+	}
+
+	return s
+}
+
+// CheckLimits returns error if limit could not be split between shards,
+// every shard should get at least one key and one byte, otherwise the store
+// would keep more than the limit
+func CheckLimits(maxKeys int, maxBytes int, shards int) error {
+	if maxKeys > 0 && maxKeys < shards {
+		return fmt.Errorf("%w: max keys %d, shards %d", ErrorLimitTooSmall, maxKeys, shards)
+	}
+
+	if maxBytes > 0 && maxBytes < shards {
+		return fmt.Errorf("%w: max bytes %d, shards %d", ErrorLimitTooSmall, maxBytes, shards)
+	}
+
+	return nil
+}
+
+// perShard rounds the share down, so shards together never keep more than the limit
+func perShard(limit int, shards int) int {
+	if limit <= 0 {
+		return 0
+	}
+
+	return max(limit/shards, 1)
+}
+
+// tooLarge reports if the entry of the size could not fit the shard even alone,
+// it would be evicted right after it is put
+func (sh *shard) tooLarge(size int) bool {
+	return sh.maxBytes > 0 && size > sh.maxBytes
+}
+
+func (sh *shard) overLimits() bool {
+	return (sh.maxKeys > 0 && len(sh.data) > sh.maxKeys) ||
+		(sh.maxBytes > 0 && sh.bytes > sh.maxBytes)
+}
+
+// evict drops keys chosen by policy until shard fits its limits,
+// every eviction is logged, so replaying the log gives the same contents
+func (sh *shard) evict(log logFunc) {
+	if sh.policy == nil {
+		return
+	}
+
+	for sh.overLimits() {
+		key, ok := sh.policy.Victim()
+		if !ok {
+			return
+		}
+
+		sh.remove(key)
+		log(Event{Type: EventEvict, Key: key})
+	}
+}
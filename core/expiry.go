@@ -0,0 +1,45 @@
+package core
+
+import (
+	"container/heap"
+)
+
+type deadline struct {
+	key string
+	at  int64
+}
+
+// deadlines is a min-heap of key deadlines. It is never updated in place:
+// when a key is overwritten or deleted its old deadline stays in the heap
+// and is skipped by the reaper if it does not match the current entry
+type deadlines []deadline
+
+func (d deadlines) Len() int           { return len(d) }
+func (d deadlines) Less(i, j int) bool { return d[i].at < d[j].at }
+func (d deadlines) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+func (d *deadlines) Push(x any) {
+	*d = append(*d, x.(deadline))
+}
+
+func (d *deadlines) Pop() any {
+	old := *d
+	n := len(old)
+	item := old[n-1]
+	*d = old[:n-1]
+
+	return item
+}
+
+func (d *deadlines) push(key string, at int64) {
+	heap.Push(d, deadline{key: key, at: at})
+}
+
+// popExpired removes and returns the earliest deadline if it is not after now
+func (d *deadlines) popExpired(now int64) (deadline, bool) {
+	if len(*d) == 0 || (*d)[0].at > now {
+		return deadline{}, false
+	}
+
+	return heap.Pop(d).(deadline), true
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"maps"
+)
+
+// HashSet sets field of the hash, hash is created if key does not exist
+func (s *Store) HashSet(key string, field string, value string) error {
+	_, _, err := s.mutate(Event{Type: EventHashSet, Key: key, Field: field, Value: []byte(value)})
+	return err
+}
+
+// HashDelete deletes field of the hash, key is deleted with its last field
+func (s *Store) HashDelete(key string, field string) error {
+	_, _, err := s.mutate(Event{Type: EventHashDelete, Key: key, Field: field})
+	return err
+}
+
+func (s *Store) HashGet(key string, field string) (value string, err error) {
+	exists := false
+
+	err = s.read(key, kindHash, func(c entry) {
+		value, exists = c.hash[field]
+	})
+
+	if err == nil && !exists {
+		err = ErrorNoSuchField
+	}
+
+	return value, err
+}
+
+func (s *Store) HashGetAll(key string) (fields map[string]string, err error) {
+	err = s.read(key, kindHash, func(c entry) {
+		fields = maps.Clone(c.hash)
+	})
+
+	return fields, err
+}
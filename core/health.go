@@ -0,0 +1,90 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrorReadOnly = errors.New("store is read-only, because changes could not be logged")
+
+// Checker is implemented by transaction loggers those could check if written
+// events would be persisted without writing an event
+type Checker interface {
+	Check() error
+}
+
+// Health of the store, it becomes read-only when the transaction logger fails
+// and it is writable again when the logger recovers
+type Health struct {
+	ReadOnly bool
+	//Error made the store read-only at Since, they are zero if store is writable
+	Error error
+	Since time.Time
+	//Degradations is how many times the store became read-only
+	Degradations uint64
+}
+
+func (s *Store) Health() Health {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	return s.health
+}
+
+// Degrade makes the store read-only after error of the transaction logger,
+// changes are rejected with ErrorReadOnly until health check succeeds
+func (s *Store) Degrade(err error) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	if s.health.ReadOnly {
+		return
+	}
+
+	s.health = Health{ReadOnly: true, Error: err, Since: time.Now(), Degradations: s.health.Degradations + 1}
+	s.readOnly.Store(true)
+
+	fmt.Println("store is read-only:", err)
+}
+
+// checkHealth makes read-only store writable if its logger is able to write again,
+// logger that could not be checked is tried by the next changes
+func (s *Store) checkHealth() {
+	if !s.readOnly.Load() {
+		return
+	}
+
+	if c, ok := s.tl.(Checker); ok {
+		if err := c.Check(); err != nil {
+			return
+		}
+	}
+
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	s.health = Health{Degradations: s.health.Degradations}
+	s.readOnly.Store(false)
+
+	fmt.Println("store is writable again")
+}
+
+// StartHealthCheck checks every interval if read-only store could be writable
+// again, it is stopped by Shutdown
+func (s *Store) StartHealthCheck(interval time.Duration) {
+	s.every(interval, s.checkHealth)
+}
+
+// writable is checked before change is applied, so read-only store is not
+// changed by changes those would not be logged
+func (s *Store) writable() error {
+	if s.follower.Load() {
+		return ErrorFollower
+	}
+	if s.readOnly.Load() {
+		return ErrorReadOnly
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadOnly(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	s.Put("key", []byte("value"))
+
+	broken := errors.New("disk is full")
+	tl.fail = broken
+
+	if _, err := s.Put("key", []byte("lost")); !errors.Is(err, ErrorNotLogged) {
+		t.Fatalf("got error %v, want %v", err, ErrorNotLogged)
+	}
+
+	health := s.Health()
+	if !health.ReadOnly || !errors.Is(health.Error, broken) || health.Degradations != 1 {
+		t.Fatalf("got health %+v, want read-only by %v", health, broken)
+	}
+
+	//nothing is changed until the logger recovers, reads still work
+	changes := []func() error{
+		func() error { _, err := s.Put("key", []byte("rejected")); return err },
+		func() error { return s.Delete("key") },
+		func() error { return s.Clear() },
+		func() error { _, err := s.Increment("counter", 1); return err },
+		func() error { _, err := s.ListPush("list", ListRight, "item"); return err },
+		func() error { _, err := s.Begin().Delete("key").Commit(); return err },
+		func() error { _, err := s.Async().Put("key", []byte("rejected")); return err },
+	}
+
+	for i, change := range changes {
+		if err := change(); !errors.Is(err, ErrorReadOnly) {
+			t.Fatalf("change %d: got error %v, want %v", i, err, ErrorReadOnly)
+		}
+	}
+	checkValue(t, s, "key", "lost")
+
+	s.StartHealthCheck(time.Millisecond)
+	defer s.Shutdown(context.Background())
+
+	//health check fails while logger fails
+	time.Sleep(10 * time.Millisecond)
+	if !s.Health().ReadOnly {
+		t.Fatal("store is writable while logger fails")
+	}
+
+	tl.mu.Lock()
+	tl.fail = nil
+	tl.mu.Unlock()
+
+	for deadline := time.Now().Add(time.Second); s.Health().ReadOnly; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("store is still read-only after logger recovered")
+		}
+	}
+
+	if _, err := s.Put("key", []byte("recovered")); err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, s, "key", "recovered")
+
+	if health = s.Health(); health.Error != nil || health.Degradations != 1 {
+		t.Fatalf("got health %+v, want writable", health)
+	}
+}
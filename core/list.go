@@ -0,0 +1,50 @@
+package core
+
+type ListEnd byte
+
+const (
+	ListLeft ListEnd = iota
+	ListRight
+)
+
+// ListPush adds item to the given end of the list and returns new length of the list,
+// list is created if key does not exist
+func (s *Store) ListPush(key string, end ListEnd, item string) (int, error) {
+	t := EventListPushRight
+	if end == ListLeft {
+		t = EventListPushLeft
+	}
+
+	_, length, err := s.mutate(Event{Type: t, Key: key, Value: []byte(item)})
+	return length, err
+}
+
+// ListPop removes and returns item from the given end of the list,
+// key is deleted after its last item is popped
+func (s *Store) ListPop(key string, end ListEnd) (string, error) {
+	t := EventListPopRight
+	if end == ListLeft {
+		t = EventListPopLeft
+	}
+
+	item, _, err := s.mutate(Event{Type: t, Key: key})
+	return item, err
+}
+
+func (s *Store) ListLen(key string) (length int, err error) {
+	err = s.read(key, kindList, func(c entry) {
+		length = c.list.len
+	})
+
+	return length, err
+}
+
+// ListRange returns items from start to stop inclusive,
+// negative indexes are counted from the end, -1 is the last item
+func (s *Store) ListRange(key string, start int, stop int) (items []string, err error) {
+	err = s.read(key, kindList, func(c entry) {
+		items = c.list.slice(start, stop)
+	})
+
+	return items, err
+}
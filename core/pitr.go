@@ -0,0 +1,107 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var ErrorHistoryCompacted = errors.New("history before the point was replaced by snapshot")
+var ErrorInvalidPoint = errors.New("point should be event ID or RFC 3339 time")
+
+// Point of the history, event is after the point if its ID or time
+// is greater than the given one, zero fields do not limit events
+type Point struct {
+	ID   uint64
+	Time time.Time
+}
+
+// ParsePoint parses event ID or RFC 3339 time
+func ParsePoint(raw string) (Point, error) {
+	if id, err := strconv.ParseUint(raw, 10, 64); err == nil {
+		return Point{ID: id}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return Point{}, fmt.Errorf("%w: %q", ErrorInvalidPoint, raw)
+	}
+
+	return Point{Time: t}, nil
+}
+
+func (p Point) before(e Event) bool {
+	return (p.ID != 0 && e.ID > p.ID) || (!p.Time.IsZero() && e.Time > p.Time.UnixNano())
+}
+
+// historyLogger reads events of another logger and drops written ones,
+// so store restored from it never changes the history
+type historyLogger struct {
+	TransactionLogger
+}
+
+func (historyLogger) WriteEvent(Event) <-chan error {
+	return logged
+}
+
+// At returns separate store with the state as it was at the point, it is
+// restored from the log of s and its own changes are not logged. Expiration
+// is checked by the current time, so keys expired since the point are absent
+func (s *Store) At(p Point) (*Store, error) {
+	//events logged before At could be still queued, they are read after flush
+	if flusher, ok := s.tl.(Flusher); ok {
+		if err := <-flusher.Flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	staged := NewShardedStore(historyLogger{s.tl}, len(s.shards))
+
+	if err := staged.restoreTo(p); err != nil {
+		return nil, err
+	}
+
+	return staged, nil
+}
+
+// Promote replaces the state of s by the state of staged, it is logged as one
+// transaction of clear followed by all keys of staged, so versions of keys keep
+// growing and watchers see the change. Staged store should not be s itself
+func (s *Store) Promote(staged *Store) (uint64, error) {
+	if err := s.writable(); err != nil {
+		return 0, err
+	}
+
+	id, ack := s.promote(staged)
+
+	return id, s.wait(ack)
+}
+
+func (s *Store) promote(staged *Store) (uint64, <-chan error) {
+	staged.lockAll()
+	defer staged.unlockAll()
+
+	s.lockAll()
+	defer s.unlockAll()
+
+	now := time.Now().UnixNano()
+
+	//snapshot marker of the dump is not needed inside transaction
+	batch := append([]Event{{Type: EventClear}}, staged.dump(now)[1:]...)
+	for i := range batch {
+		batch[i].ID = 0
+	}
+
+	var ack <-chan error
+
+	e := Event{Type: EventTxn, Batch: batch}
+	e.ID, ack = s.log(e)
+	s.apply(e, now)
+
+	for _, sh := range s.shards {
+		sh.evict(s.log)
+	}
+
+	return e.ID, ack
+}
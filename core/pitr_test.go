@@ -0,0 +1,149 @@
+package core
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAt(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	s.Put("key", []byte("first"))
+	time.Sleep(time.Millisecond)
+	between := time.Now()
+	time.Sleep(time.Millisecond)
+
+	second, _ := s.Put("key", []byte("second"))
+	if _, err := s.ListPush("list", ListRight, "item"); err != nil {
+		t.Fatal(err)
+	}
+	s.Clear()
+	s.Put("after", []byte("clear"))
+
+	staged, err := s.At(Point{Time: between})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, staged, "key", "first")
+	checkNoSuchKey(t, staged, "after")
+
+	staged, err = s.At(Point{ID: second + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, staged, "key", "second")
+	checkNoSuchKey(t, staged, "after")
+
+	//changes of restored store are not logged
+	logged := tl.LogSize()
+	staged.Put("staged", []byte("value"))
+	if size := tl.LogSize(); size != logged {
+		t.Fatalf("got %d events, want %d", size, logged)
+	}
+
+	//the live store is not changed until promote
+	checkNoSuchKey(t, s, "key")
+
+	version, err := s.Promote(staged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkValue(t, s, "key", "second")
+	checkValue(t, s, "staged", "value")
+	checkNoSuchKey(t, s, "after")
+
+	if item, _ := s.GetItem("key"); item.Version != version {
+		t.Fatalf("got version %d, want %d", item.Version, version)
+	}
+	if items, err := s.ListRange("list", 0, -1); err != nil || !slices.Equal(items, []string{"item"}) {
+		t.Fatalf("got %v (%v), want [item]", items, err)
+	}
+
+	restored := NewStore(tl)
+	if err = restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, restored, "key", "second")
+	checkNoSuchKey(t, restored, "after")
+}
+
+func TestAtCompacted(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	first, _ := s.Put("key", []byte("first"))
+	s.Put("key", []byte("second"))
+
+	if err := s.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.At(Point{ID: first}); !errors.Is(err, ErrorHistoryCompacted) {
+		t.Fatalf("got error %v, want %v", err, ErrorHistoryCompacted)
+	}
+
+	s.Put("key", []byte("third"))
+
+	staged, err := s.At(Point{ID: first + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, staged, "key", "second")
+}
+
+func TestParsePoint(t *testing.T) {
+	if p, err := ParsePoint("42"); err != nil || p.ID != 42 {
+		t.Fatalf("got %v (%v), want ID 42", p, err)
+	}
+
+	want := time.Date(2024, 10, 27, 12, 0, 0, 0, time.UTC)
+	if p, err := ParsePoint("2024-10-27T12:00:00Z"); err != nil || !p.Time.Equal(want) {
+		t.Fatalf("got %v (%v), want time %v", p, err, want)
+	}
+
+	if _, err := ParsePoint("yesterday"); !errors.Is(err, ErrorInvalidPoint) {
+		t.Fatalf("got error %v, want %v", err, ErrorInvalidPoint)
+	}
+}
+
+// queuedLogger acks events at once, but they are read only after flush
+// like events still queued by FileLogger
+type queuedLogger struct {
+	memLogger
+	queued []Event
+}
+
+func (l *queuedLogger) WriteEvent(e Event) <-chan error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.queued = append(l.queued, e)
+
+	return Logged()
+}
+
+func (l *queuedLogger) Flush() <-chan error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, l.queued...)
+	l.queued = nil
+
+	return Logged()
+}
+
+func TestAtQueued(t *testing.T) {
+	s := NewStore(&queuedLogger{})
+
+	version, _ := s.Put("key", []byte("queued"))
+
+	staged, err := s.At(Point{ID: version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, staged, "key", "queued")
+}
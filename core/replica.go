@@ -0,0 +1,78 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrorFollower = errors.New("store is a follower, changes are made by the leader")
+
+// Follow makes the store a follower of the leader, changes of clients are rejected
+// with ErrorFollower and the store is changed only by Replicate. Follower does not
+// expire or evict keys by itself, expired keys are hidden until expire of the leader
+// comes, so IDs of the follower are the IDs of the leader
+func (s *Store) Follow() {
+	s.follower.Store(true)
+}
+
+func (s *Store) IsFollower() bool {
+	return s.follower.Load()
+}
+
+// LastID returns ID of the last logged or replicated event
+func (s *Store) LastID() uint64 {
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+
+	return s.lastID
+}
+
+// Replicate applies event of the leader with its ID and writes it to the log
+// of the store, so follower restarts from it. Events those are already applied
+// are skipped. Snapshot replaces the whole state by keys of its Batch and
+// compacts the log, watchers of keys are disconnected with ErrorStateReplaced
+func (s *Store) Replicate(e Event) error {
+	now := time.Now().UnixNano()
+
+	if e.Type == EventSnapshot {
+		s.snapshotMu.Lock()
+		defer s.snapshotMu.Unlock()
+	}
+
+	s.lockAll()
+	s.seqMu.Lock()
+
+	if e.Type != EventSnapshot && e.ID <= s.lastID {
+		s.seqMu.Unlock()
+		s.unlockAll()
+
+		return nil
+	}
+
+	ack := Logged()
+	s.lastID = e.ID
+
+	if e.Type == EventSnapshot {
+		for _, sh := range s.shards {
+			sh.clear()
+		}
+
+		for _, op := range e.Batch {
+			s.apply(op, now)
+		}
+
+		if c, ok := s.tl.(Compactor); ok {
+			ack = c.Compact(s.dump(now))
+		}
+	} else {
+		s.apply(e, now)
+		ack = s.tl.WriteEvent(e)
+	}
+
+	s.notify(e)
+
+	s.seqMu.Unlock()
+	s.unlockAll()
+
+	return s.wait(ack)
+}
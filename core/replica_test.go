@@ -0,0 +1,97 @@
+package core
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReplicate(t *testing.T) {
+	leader := NewStore(&memLogger{})
+
+	leader.Put("a", []byte("1"))
+	leader.Put("b", []byte("2"))
+	if err := leader.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	leader.Delete("a")
+
+	tl := &memLogger{}
+	follower := NewStore(tl)
+	follower.Put("stale", []byte("old"))
+	follower.Follow()
+
+	watcher := follower.Watch("stale", false, 10)
+
+	feed, err := leader.Changes(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer feed.Close()
+
+	for range 2 {
+		select {
+		case e := <-feed.Events():
+			if err = follower.Replicate(e); err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no change of the leader")
+		}
+	}
+
+	checkNoSuchKey(t, follower, "a")
+	checkValue(t, follower, "b", "2")
+	checkNoSuchKey(t, follower, "stale")
+
+	if follower.LastID() != leader.LastID() {
+		t.Fatalf("got last ID %d, want %d", follower.LastID(), leader.LastID())
+	}
+
+	for range watcher.Events() {
+	}
+	if !errors.Is(watcher.Err(), ErrorStateReplaced) {
+		t.Fatalf("got error %v, want %v", watcher.Err(), ErrorStateReplaced)
+	}
+
+	if _, err = follower.Put("c", []byte("3")); !errors.Is(err, ErrorFollower) {
+		t.Fatalf("got error %v, want %v", err, ErrorFollower)
+	}
+
+	//follower restarts from its own log with IDs of the leader
+	restarted := NewStore(tl)
+	if err = restarted.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, restarted, "b", "2")
+	checkNoSuchKey(t, restarted, "a")
+
+	if restarted.LastID() != leader.LastID() {
+		t.Fatalf("got last ID %d after restart, want %d", restarted.LastID(), leader.LastID())
+	}
+}
+
+func TestReplicateAfterExpiredRead(t *testing.T) {
+	tl := &memLogger{}
+	follower := NewStore(tl)
+	follower.Follow()
+
+	deadline := time.Now().Add(10 * time.Millisecond).UnixNano()
+	if err := follower.Replicate(Event{ID: 1, Type: EventPutWithTTL, Key: "ttl", Value: []byte("v"), Deadline: deadline}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	checkNoSuchKey(t, follower, "ttl")
+	follower.reap()
+
+	if err := follower.Replicate(Event{ID: 2, Type: EventPut, Key: "x", Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, follower, "x", "v")
+
+	if got := tl.types(); !slices.Equal(got, []EventType{EventPutWithTTL, EventPut}) {
+		t.Fatalf("got logged types %v, want only replicated ones", got)
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrorInvalidCursor = errors.New("invalid cursor")
+var ErrorInvalidLimit = errors.New("limit should be positive")
+
+// cursor points to the last returned key of the shard, keys of every shard
+// are scanned in lexicographical order, so cursor stays valid whatever
+// is written between calls
+type cursor struct {
+	shard int
+	key   string
+	//started is false until any key of the shard is returned
+	started bool
+}
+
+func (c cursor) String() string {
+	if !c.started {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(c.shard) + ":" + c.key))
+}
+
+func (s *Store) parseCursor(raw string) (cursor, error) {
+	if raw == "" {
+		return cursor{}, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor{}, ErrorInvalidCursor
+	}
+
+	index, key, found := strings.Cut(string(decoded), ":")
+	if !found {
+		return cursor{}, ErrorInvalidCursor
+	}
+
+	c := cursor{key: key, started: true}
+	if c.shard, err = strconv.Atoi(index); err != nil || c.shard < 0 || c.shard >= len(s.shards) {
+		return cursor{}, ErrorInvalidCursor
+	}
+
+	return c, nil
+}
+
+// scan returns up to limit live keys with prefix those go after the key
+// of cursor in lexicographical order
+func (sh *shard) scan(prefix string, c cursor, limit int, now int64) []string {
+	sh.RLock()
+	defer sh.RUnlock()
+
+	var keys []string
+
+	for key, e := range sh.data {
+		if strings.HasPrefix(key, prefix) && (!c.started || key > c.key) && !e.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	return keys[:min(limit, len(keys))]
+}
+
+// Scan returns up to limit keys with given prefix and cursor of the next page,
+// empty cursor starts scan and is returned when scan is finished. Only one shard
+// is locked at a time, keys those exist during whole scan are returned exactly once,
+// keys those are added or deleted meanwhile could be returned or not
+func (s *Store) Scan(prefix string, rawCursor string, limit int) ([]string, string, error) {
+	if limit <= 0 {
+		return nil, "", ErrorInvalidLimit
+	}
+
+	c, err := s.parseCursor(rawCursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now().UnixNano()
+	keys := make([]string, 0, min(limit, 1024))
+
+	for ; c.shard < len(s.shards); c = (cursor{shard: c.shard + 1}) {
+		keys = append(keys, s.shards[c.shard].scan(prefix, c, limit-len(keys), now)...)
+
+		if len(keys) == limit {
+			next := cursor{shard: c.shard, key: keys[len(keys)-1], started: true}
+			return keys, next.String(), nil
+		}
+	}
+
+	return keys, "", nil
+}
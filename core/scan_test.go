@@ -0,0 +1,125 @@
+package core
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func scanAll(t *testing.T, s *Store, prefix string, limit int) []string {
+	t.Helper()
+
+	var all []string
+	cursor := ""
+
+	for {
+		keys, next, err := s.Scan(prefix, cursor, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) > limit {
+			t.Fatalf("got %d keys, limit is %d", len(keys), limit)
+		}
+
+		all = append(all, keys...)
+
+		if next == "" {
+			return all
+		}
+
+		cursor = next
+	}
+}
+
+func TestScan(t *testing.T) {
+	s := NewShardedStore(&memLogger{}, 4)
+
+	var want []string
+	for i := 0; i < 100; i++ {
+		key := "user:" + strconv.Itoa(i)
+		s.Put(key, []byte("value"))
+		want = append(want, key)
+	}
+
+	slices.Sort(want)
+
+	s.Put("", []byte("empty key"))
+	s.Put("other", []byte("value"))
+
+	for _, limit := range []int{1, 7, 100, 1000} {
+		got := scanAll(t, s, "user:", limit)
+
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Fatalf("limit %d: got %d keys, want %d", limit, len(got), len(want))
+		}
+	}
+
+	if got := scanAll(t, s, "", 3); len(got) != len(want)+2 {
+		t.Fatalf("got %d keys, want %d", len(got), len(want)+2)
+	}
+
+	if _, _, err := s.Scan("", "not a cursor", 10); !errors.Is(err, ErrorInvalidCursor) {
+		t.Fatalf("got error %v, want %v", err, ErrorInvalidCursor)
+	}
+	if _, _, err := s.Scan("", "", 0); !errors.Is(err, ErrorInvalidLimit) {
+		t.Fatalf("got error %v, want %v", err, ErrorInvalidLimit)
+	}
+}
+
+func TestScanConcurrentWrites(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	var stable []string
+	for i := 0; i < 500; i++ {
+		key := "stable:" + strconv.Itoa(i)
+		s.Put(key, []byte("value"))
+		stable = append(stable, key)
+	}
+
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			key := "stable:" + strconv.Itoa(1000+i%100)
+			if i%2 == 0 {
+				s.Put(key, []byte("value"))
+			} else {
+				s.Delete(key)
+			}
+		}
+	}()
+
+	got := scanAll(t, s, "stable:", 10)
+
+	close(stop)
+	wg.Wait()
+
+	seen := make(map[string]int)
+	for _, key := range got {
+		seen[key]++
+	}
+
+	for _, key := range stable {
+		if seen[key] != 1 {
+			t.Fatalf("key %q returned %d times", key, seen[key])
+		}
+	}
+	for key, times := range seen {
+		if times != 1 {
+			t.Fatalf("key %q returned %d times", key, times)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"errors"
+)
+
+// SetAdd adds member to the set and reports whether it was not there before,
+// set is created if key does not exist
+func (s *Store) SetAdd(key string, member string) (bool, error) {
+	_, _, err := s.mutate(Event{Type: EventSetAdd, Key: key, Value: []byte(member)})
+	if errors.Is(err, errNotChanged) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// SetRemove removes member from the set and reports whether it was there,
+// key is deleted with its last member
+func (s *Store) SetRemove(key string, member string) (bool, error) {
+	_, _, err := s.mutate(Event{Type: EventSetRemove, Key: key, Value: []byte(member)})
+	if errors.Is(err, errNotChanged) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (s *Store) SetIsMember(key string, member string) (exists bool, err error) {
+	err = s.read(key, kindSet, func(c entry) {
+		exists = c.set.Contains(member)
+	})
+
+	return exists, err
+}
+
+// SetMembers returns all members in no particular order
+func (s *Store) SetMembers(key string) (members []string, err error) {
+	err = s.read(key, kindSet, func(c entry) {
+		members = c.set.Members()
+	})
+
+	return members, err
+}
+
+func (s *Store) SetRandomMember(key string) (member string, err error) {
+	err = s.read(key, kindSet, func(c entry) {
+		member = c.set.Random()
+	})
+
+	return member, err
+}
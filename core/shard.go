@@ -0,0 +1,103 @@
+package core
+
+import (
+	"sync"
+)
+
+// shard owns a part of the keys with its own lock, so operations
+// on keys from different shards do not wait for each other
+type shard struct {
+	sync.RWMutex
+	data      map[string]entry
+	deadlines deadlines
+
+	//policy is touched by readers under RLock, so it has its own lock for that
+	policyMu sync.Mutex
+	policy   EvictionPolicy
+	maxKeys  int
+	maxBytes int
+	bytes    int
+}
+
+func newShard() *shard {
+	return &shard{data: make(map[string]entry)}
+}
+
+// set, remove and clear keep deadlines, eviction policy and
+// size of the data consistent with the map, caller should hold the lock
+func (sh *shard) set(key string, e entry) {
+	old, exists := sh.data[key]
+	if exists {
+		sh.bytes -= entrySize(key, old)
+	}
+
+	sh.data[key] = e
+	sh.bytes += entrySize(key, e)
+
+	if e.deadline != 0 && (!exists || old.deadline != e.deadline) {
+		sh.deadlines.push(key, e.deadline)
+	}
+	if sh.policy != nil {
+		sh.policy.Add(key, e.deadline)
+	}
+}
+
+func (sh *shard) remove(key string) {
+	old, ok := sh.data[key]
+	if !ok {
+		return
+	}
+
+	delete(sh.data, key)
+	sh.bytes -= entrySize(key, old)
+
+	if sh.policy != nil {
+		sh.policy.Remove(key)
+	}
+}
+
+func (sh *shard) clear() {
+	clear(sh.data)
+	sh.deadlines = nil
+	sh.bytes = 0
+
+	if sh.policy != nil {
+		sh.policy.Clear()
+	}
+}
+
+// touch tells eviction policy that key was read, caller should hold at least RLock
+func (sh *shard) touch(key string) {
+	if sh.policy == nil {
+		return
+	}
+
+	sh.policyMu.Lock()
+	sh.policy.Touch(key)
+	sh.policyMu.Unlock()
+}
+
+// expire removes key if it is still expired, key could be overwritten
+// between the moment when caller saw it expired and taking the lock
+func (sh *shard) expire(log logFunc, key string, now int64) {
+	sh.Lock()
+	defer sh.Unlock()
+
+	if e, ok := sh.data[key]; ok && e.expired(now) {
+		sh.remove(key)
+		log(Event{Type: EventExpire, Key: key})
+	}
+}
+
+func (sh *shard) reap(log logFunc, now int64) {
+	sh.Lock()
+	defer sh.Unlock()
+
+	for d, ok := sh.deadlines.popExpired(now); ok; d, ok = sh.deadlines.popExpired(now) {
+		//deadline is stale if key was overwritten or deleted after it was pushed
+		if e, exists := sh.data[d.key]; exists && e.deadline == d.at {
+			sh.remove(d.key)
+			log(Event{Type: EventExpire, Key: d.key})
+		}
+	}
+}
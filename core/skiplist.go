@@ -0,0 +1,245 @@
+package core
+
+import (
+	"math/rand"
+)
+
+const (
+	skipListMaxLevel = 32
+	//probability to promote node to the next level
+	skipListP = 0.25
+)
+
+func (m ZMember) less(other ZMember) bool {
+	if m.Score != other.Score {
+		return m.Score < other.Score
+	}
+
+	return m.Member < other.Member
+}
+
+type skipLevel struct {
+	forward *skipNode
+	//number of nodes between this node and forward one, it allows to find rank in O(log n)
+	span int
+}
+
+type skipNode struct {
+	ZMember
+	backward *skipNode
+	levels   []skipLevel
+}
+
+// skipList keeps members ordered by score and then by member,
+// it is the same structure as redis uses for sorted sets
+type skipList struct {
+	head   *skipNode
+	tail   *skipNode
+	length int
+	level  int
+}
+
+func newSkipList() *skipList {
+	return &skipList{
+		head:  &skipNode{levels: make([]skipLevel, skipListMaxLevel)},
+		level: 1,
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skipListMaxLevel && rand.Float64() < skipListP {
+		level++
+	}
+
+	return level
+}
+
+func (l *skipList) insert(m ZMember) {
+	var update [skipListMaxLevel]*skipNode
+	var rank [skipListMaxLevel]int
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		if i < l.level-1 {
+			rank[i] = rank[i+1]
+		}
+
+		for x.levels[i].forward != nil && x.levels[i].forward.less(m) {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].forward
+		}
+
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > l.level {
+		for i := l.level; i < level; i++ {
+			update[i] = l.head
+			update[i].levels[i].span = l.length
+		}
+
+		l.level = level
+	}
+
+	x = &skipNode{ZMember: m, levels: make([]skipLevel, level)}
+
+	for i := 0; i < level; i++ {
+		x.levels[i].forward = update[i].levels[i].forward
+		update[i].levels[i].forward = x
+
+		x.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+
+	for i := level; i < l.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != l.head {
+		x.backward = update[0]
+	}
+
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x
+	} else {
+		l.tail = x
+	}
+
+	l.length++
+}
+
+func (l *skipList) delete(m ZMember) bool {
+	var update [skipListMaxLevel]*skipNode
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.less(m) {
+			x = x.levels[i].forward
+		}
+
+		update[i] = x
+	}
+
+	x = x.levels[0].forward
+	if x == nil || x.ZMember != m {
+		return false
+	}
+
+	for i := 0; i < l.level; i++ {
+		if update[i].levels[i].forward == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].forward = x.levels[i].forward
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x.backward
+	} else {
+		l.tail = x.backward
+	}
+
+	for l.level > 1 && l.head.levels[l.level-1].forward == nil {
+		l.level--
+	}
+
+	l.length--
+
+	return true
+}
+
+// rank returns 0-based position of the member, member should be in the list
+func (l *skipList) rank(m ZMember) int {
+	rank := 0
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !m.less(x.levels[i].forward.ZMember) {
+			rank += x.levels[i].span
+			x = x.levels[i].forward
+		}
+
+		if x != l.head && x.ZMember == m {
+			return rank - 1
+		}
+	}
+
+	return -1
+}
+
+// byRank returns node at 0-based position or nil
+func (l *skipList) byRank(rank int) *skipNode {
+	if rank < 0 || rank >= l.length {
+		return nil
+	}
+
+	traversed := 0
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= rank+1 {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+
+		if traversed == rank+1 {
+			return x
+		}
+	}
+
+	return nil
+}
+
+// firstInRange returns the first node with score not less than min or nil
+func (l *skipList) firstInRange(min float64) *skipNode {
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.Score < min {
+			x = x.levels[i].forward
+		}
+	}
+
+	return x.levels[0].forward
+}
+
+// rangeByRank returns members from start to stop inclusive,
+// negative ranks are counted from the end like in redis ZRANGE
+func (l *skipList) rangeByRank(start int, stop int) []ZMember {
+	if start < 0 {
+		start = max(l.length+start, 0)
+	}
+	if stop < 0 {
+		stop = l.length + stop
+	}
+
+	stop = min(stop, l.length-1)
+
+	if start > stop {
+		return []ZMember{}
+	}
+
+	members := make([]ZMember, 0, stop-start+1)
+	for x := l.byRank(start); x != nil && len(members) < stop-start+1; x = x.levels[0].forward {
+		members = append(members, x.ZMember)
+	}
+
+	return members
+}
+
+// rangeByScore returns members with min <= score <= max
+func (l *skipList) rangeByScore(min float64, max float64) []ZMember {
+	members := []ZMember{}
+
+	for x := l.firstInRange(min); x != nil && x.Score <= max; x = x.levels[0].forward {
+		members = append(members, x.ZMember)
+	}
+
+	return members
+}
+
+func (l *skipList) first() *skipNode {
+	return l.head.levels[0].forward
+}
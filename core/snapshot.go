@@ -0,0 +1,135 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrorSnapshotNotSupported = errors.New("transaction logger does not support snapshots")
+
+// snapshotCheck is how often size of the log is checked by StartSnapshots
+const snapshotCheck = time.Second
+
+// Compactor is implemented by transaction loggers those could replace history
+// of events by snapshot of the state
+type Compactor interface {
+	// Compact is called instead of WriteEvent at the point of the log where the state
+	// was taken, so events written before it are included in the state and events written
+	// after it are not. State starts with EventSnapshot with ID of the last included event,
+	// other events recreate keys with their versions as IDs. Returned channel gets
+	// result of saving the snapshot
+	Compact(state []Event) <-chan error
+	// LogSize returns size of the log written since the last snapshot
+	LogSize() int64
+}
+
+// dump returns events those recreate the current state, caller should hold locks
+// of all shards, every event has version of its key as ID
+func (s *Store) dump(now int64) []Event {
+	state := []Event{{Type: EventSnapshot, ID: s.lastID, Time: now}}
+
+	for _, sh := range s.shards {
+		for key, e := range sh.data {
+			if e.expired(now) {
+				continue
+			}
+
+			event := Event{ID: e.version, Key: key}
+
+			switch e.kind {
+			case kindString:
+				event.Type, event.Value, event.ContentType = EventPut, e.value, e.contentType
+
+				if e.deadline != 0 {
+					event.Type, event.Deadline = EventPutWithTTL, e.deadline
+				}
+
+				state = append(state, event)
+			case kindList:
+				event.Type = EventListPushRight
+
+				for _, item := range e.list.slice(0, -1) {
+					event.Value = []byte(item)
+					state = append(state, event)
+				}
+			case kindHash:
+				event.Type = EventHashSet
+
+				for field, value := range e.hash {
+					event.Field, event.Value = field, []byte(value)
+					state = append(state, event)
+				}
+			case kindSet:
+				event.Type = EventSetAdd
+
+				for _, member := range e.set.Members() {
+					event.Value = []byte(member)
+					state = append(state, event)
+				}
+			case kindZSet:
+				event.Type = EventZSetAdd
+
+				for member, score := range e.zset.scores {
+					event.Field, event.Value = member, []byte(formatScore(score))
+					state = append(state, event)
+				}
+			}
+		}
+	}
+
+	return state
+}
+
+// Snapshot saves the current state through the transaction logger, so events
+// before it are not replayed by Restore anymore. Store is locked only while
+// the state is copied, it is saved after that
+func (s *Store) Snapshot() error {
+	c, ok := s.tl.(Compactor)
+	if !ok {
+		return ErrorSnapshotNotSupported
+	}
+
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+
+	s.lockAll()
+	s.seqMu.Lock()
+
+	done := c.Compact(s.dump(time.Now().UnixNano()))
+
+	s.seqMu.Unlock()
+	s.unlockAll()
+
+	if err := <-done; err != nil {
+		return fmt.Errorf("save snapshot was failed: %w", err)
+	}
+
+	return nil
+}
+
+// StartSnapshots takes snapshot every interval and when size of the log exceeds
+// maxLogSize, zero disables the trigger. It is stopped by Shutdown
+func (s *Store) StartSnapshots(interval time.Duration, maxLogSize int64) {
+	c, ok := s.tl.(Compactor)
+	if !ok || (interval <= 0 && maxLogSize <= 0) {
+		return
+	}
+
+	last := time.Now()
+
+	s.every(snapshotCheck, func() {
+		byTime := interval > 0 && time.Since(last) >= interval
+		bySize := maxLogSize > 0 && c.LogSize() >= maxLogSize
+
+		if !byTime && !bySize {
+			return
+		}
+
+		if err := s.Snapshot(); err != nil {
+			fmt.Println(err)
+		}
+
+		last = time.Now()
+	})
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	for i := 0; i < 10; i++ {
+		s.Put("overwritten", []byte{byte(i)})
+	}
+
+	if _, err := s.PutItem("typed", Item{Value: []byte("{}"), ContentType: "application/json", TTL: time.Hour}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PutWithTTL("expired", []byte("value"), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range []string{"a", "b", "c"} {
+		if _, err := s.ListPush("list", ListRight, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.HashSet("hash", "field", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetAdd("set", "member"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ZAdd("zset", "member", 1.5); err != nil {
+		t.Fatal(err)
+	}
+
+	//the last event is delete, so the last ID is not a version of any key
+	s.Put("deleted", []byte("value"))
+	s.Delete("deleted")
+
+	time.Sleep(5 * time.Millisecond)
+
+	if err := s.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	if size := tl.LogSize(); size > 10 {
+		t.Fatalf("got %d events after snapshot, want only state", size)
+	}
+
+	after, _ := s.Put("after", []byte("snapshot"))
+
+	restored := NewStore(tl)
+	if err := restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkValue(t, restored, "overwritten", string([]byte{9}))
+	checkValue(t, restored, "after", "snapshot")
+	checkNoSuchKey(t, restored, "expired")
+	checkNoSuchKey(t, restored, "deleted")
+
+	for _, key := range []string{"overwritten", "typed", "after"} {
+		want, _ := s.GetItem(key)
+		got, err := restored.GetItem(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != want.Version || got.ContentType != want.ContentType {
+			t.Fatalf("key %q: got version %d of type %q, want %d of type %q",
+				key, got.Version, got.ContentType, want.Version, want.ContentType)
+		}
+	}
+
+	if items, err := restored.ListRange("list", 0, -1); err != nil || !slices.Equal(items, []string{"a", "b", "c"}) {
+		t.Fatalf("got %v (%v), want [a b c]", items, err)
+	}
+	if fields, err := restored.HashGetAll("hash"); err != nil || !maps.Equal(fields, map[string]string{"field": "value"}) {
+		t.Fatalf("got %v (%v), want map[field:value]", fields, err)
+	}
+	if exists, err := restored.SetIsMember("set", "member"); err != nil || !exists {
+		t.Fatalf("got %v (%v), want member", exists, err)
+	}
+	if score, err := restored.ZScore("zset", "member"); err != nil || score != 1.5 {
+		t.Fatalf("got %v (%v), want 1.5", score, err)
+	}
+
+	//IDs continue after the last event before snapshot, not after the last version
+	if version, _ := restored.Put("new", []byte("value")); version != after+1 {
+		t.Fatalf("got version %d, want %d", version, after+1)
+	}
+}
+
+func TestSnapshotNotSupported(t *testing.T) {
+	s := NewStore(nopLogger{})
+
+	if err := s.Snapshot(); !errors.Is(err, ErrorSnapshotNotSupported) {
+		t.Fatalf("got error %v, want %v", err, ErrorSnapshotNotSupported)
+	}
+}
+
+func TestSnapshotByLogSize(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	for i := 0; i < 100; i++ {
+		s.Put("key", []byte("value"))
+	}
+
+	s.StartSnapshots(0, 50)
+	defer s.Shutdown(context.Background())
+
+	deadline := time.Now().Add(3 * snapshotCheck)
+	for tl.LogSize() >= 50 {
+		if time.Now().After(deadline) {
+			t.Fatal("snapshot was not taken")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
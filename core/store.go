@@ -1,32 +1,122 @@
 package core
 
 import (
+	"bytes"
+	"cache/set"
 	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var ErrorNoSuchKey = errors.New("no such key")
+var ErrorInvalidTTL = errors.New("ttl should be positive")
+
+// ErrorNotLogged means that change is applied, but it was not written to the
+// transaction logger, so it could be lost on restart
+var ErrorNotLogged = errors.New("change is not logged")
+
+const DefaultShards = 16
 
 type TransactionLogger interface {
-	WriteEvent(t EventType, key string, value string)
+	// WriteEvent queues the event, returned channel gets result of writing it
+	WriteEvent(e Event) <-chan error
 	ReadEvents() (<-chan Event, <-chan error)
 	Start() <-chan error
 	Shutdown(ctx context.Context) error
 }
 
+var logged = func() chan error {
+	ack := make(chan error)
+	close(ack)
+	return ack
+}()
+
+// Logged returns ack of the event that is logged already or needs no writing
+func Logged() <-chan error {
+	return logged
+}
+
+type entry struct {
+	kind        kind
+	value       []byte
+	contentType string
+	list        *deque
+	hash        map[string]string
+	set         *set.Set
+	zset        *zset
+	//total length of items of collection, it is not used by strings
+	size int
+	//unix time in nanoseconds, zero means that entry never expires
+	deadline int64
+	//ID of the event that wrote the value
+	version uint64
+}
+
+type Item struct {
+	//value is shared with the store and should not be modified
+	Value       []byte
+	ContentType string
+	//zero if key never expires
+	TTL     time.Duration
+	Version uint64
+}
+
+func (e entry) expired(now int64) bool {
+	return e.deadline != 0 && e.deadline <= now
+}
+
+// Store is a view of the store state, views differ only in waiting for the log
 type Store struct {
-	sync.RWMutex
-	data map[string]string
-	tl   TransactionLogger
+	*state
+	//async writes do not wait until their events are logged
+	async bool
+}
+
+type state struct {
+	shards []*shard
+	tl     TransactionLogger
+
+	//seqMu keeps events in the log and notifications of watchers ordered by their IDs
+	seqMu    sync.Mutex
+	lastID   uint64
+	watchers map[*Watcher]struct{}
+
+	//snapshotMu lets only one snapshot to be taken at a time
+	snapshotMu sync.Mutex
+
+	//readOnly is set while health tells that the logger fails
+	readOnly atomic.Bool
+	healthMu sync.Mutex
+	health   Health
+
+	//follower is changed only by events of the leader
+	follower atomic.Bool
+
+	//stop is closed by Shutdown to stop background work
+	stop       chan struct{}
+	background sync.WaitGroup
 }
 
 func NewStore(tl TransactionLogger) *Store {
-	return &Store{
-		data: make(map[string]string),
-		tl:   tl,
+	return NewShardedStore(tl, DefaultShards)
+}
+
+// NewShardedStore creates store which keys are partitioned between
+// given number of shards, every shard has its own lock
+func NewShardedStore(tl TransactionLogger, shards int) *Store {
+	s := &Store{state: &state{
+		shards: make([]*shard, max(shards, 1)),
+		tl:     tl,
+	}}
+
+	for i := range s.shards {
+		s.shards[i] = newShard()
 	}
+
+	return s
 }
 
 func (s *Store) WithTransactionLogger(tl TransactionLogger) *Store {
@@ -34,67 +124,377 @@ func (s *Store) WithTransactionLogger(tl TransactionLogger) *Store {
 	return s
 }
 
-func (s *Store) Get(key string) (string, error) {
-	s.RLock()
-	defer s.RUnlock()
+// Async returns view of the same store which writes return without waiting
+// until their events are logged, so errors of logging are not returned by them
+func (s *Store) Async() *Store {
+	return &Store{state: s.state, async: true}
+}
+
+func (s *Store) Shards() int {
+	return len(s.shards)
+}
+
+func (s *Store) shardIndex(key string) int {
+	//inlined 32-bit FNV-1a, hash/fnv allocates on every call
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+
+	return int(hash % uint32(len(s.shards)))
+}
+
+func (s *Store) shard(key string) *shard {
+	return s.shards[s.shardIndex(key)]
+}
+
+// lockAll locks every shard always in the same order, so operations on
+// the whole store are atomic for clients and do not deadlock each other
+func (s *Store) lockAll() {
+	for _, sh := range s.shards {
+		sh.Lock()
+	}
+}
+
+func (s *Store) unlockAll() {
+	for _, sh := range s.shards {
+		sh.Unlock()
+	}
+}
+
+type logFunc func(e Event) (uint64, <-chan error)
+
+// log assigns next ID to the event, writes it to the transaction logger and
+// notifies watchers, ID of the event becomes version of the key it changed.
+// Caller should wait for the returned ack after it releases locks
+func (s *Store) log(e Event) (uint64, <-chan error) {
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+
+	s.lastID++
+	e.ID, e.Time = s.lastID, time.Now().UnixNano()
+	ack := s.tl.WriteEvent(e)
+	s.notify(e)
+
+	return e.ID, ack
+}
+
+// wait waits until the event is logged, writes of async view do not wait,
+// failed write makes the store read-only
+func (s *Store) wait(ack <-chan error) error {
+	if s.async || ack == nil {
+		return nil
+	}
+
+	if err := <-ack; err != nil {
+		s.Degrade(err)
+		return fmt.Errorf("%w: %w", ErrorNotLogged, err)
+	}
+
+	return nil
+}
+
+func (s *Store) Get(key string) ([]byte, error) {
+	item, err := s.GetItem(key)
+	return item.Value, err
+}
+
+// GetWithTTL returns value and remaining lifetime of the key,
+// ttl is zero if key never expires
+func (s *Store) GetWithTTL(key string) ([]byte, time.Duration, error) {
+	item, err := s.GetItem(key)
+	return item.Value, item.TTL, err
+}
+
+func (s *Store) GetItem(key string) (Item, error) {
+	sh := s.shard(key)
+
+	sh.RLock()
+	e, ok := sh.data[key]
+	if ok {
+		sh.touch(key)
+	}
+	sh.RUnlock()
 
-	value, ok := s.data[key]
 	if !ok {
-		return "", ErrorNoSuchKey
+		return Item{}, ErrorNoSuchKey
+	}
+
+	now := time.Now().UnixNano()
+
+	if e.expired(now) {
+		//follower only hides expired key, expire of the leader removes it
+		if !s.follower.Load() {
+			sh.expire(s.log, key, now)
+		}
+
+		return Item{}, ErrorNoSuchKey
+	}
+
+	if e.kind != kindString {
+		return Item{}, ErrorWrongType
+	}
+
+	item := Item{Value: e.value, ContentType: e.contentType, Version: e.version}
+	if e.deadline != 0 {
+		item.TTL = time.Duration(e.deadline - now)
+	}
+
+	return item, nil
+}
+
+// Put returns new version of the key, value is copied
+func (s *Store) Put(key string, value []byte) (uint64, error) {
+	return s.put(key, Item{Value: value}, nil)
+}
+
+func (s *Store) PutWithTTL(key string, value []byte, ttl time.Duration) (uint64, error) {
+	if ttl <= 0 {
+		return 0, ErrorInvalidTTL
 	}
 
-	return value, nil
+	return s.put(key, Item{Value: value, TTL: ttl}, nil)
 }
 
-func (s *Store) Put(key string, value string) {
-	s.Lock()
-	defer s.Unlock()
+// put writes value and media type of the item, version of the item is ignored,
+// version is returned with ErrorNotLogged, because the value is written anyway
+func (s *Store) put(key string, item Item, cond Condition) (uint64, error) {
+	version, ack, err := s.putLocked(key, item, cond)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, s.wait(ack)
+}
+
+func (s *Store) putLocked(key string, item Item, cond Condition) (uint64, <-chan error, error) {
+	if err := s.writable(); err != nil {
+		return 0, nil, err
+	}
+
+	sh := s.shard(key)
+	if sh.tooLarge(len(key) + len(item.Value)) {
+		return 0, nil, ErrorTooLarge
+	}
+
+	sh.Lock()
+	defer sh.Unlock()
+
+	now := time.Now()
+
+	if cond != nil {
+		old, exists := sh.data[key]
+		if err := cond(old.version, exists && !old.expired(now.UnixNano())); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	e := entry{value: bytes.Clone(item.Value), contentType: item.ContentType}
+	event := Event{Type: EventPut, Key: key, Value: e.value, ContentType: e.contentType}
+
+	if item.TTL != 0 {
+		e.deadline = now.Add(item.TTL).UnixNano()
+		event.Type, event.Deadline = EventPutWithTTL, e.deadline
+	}
+
+	var ack <-chan error
+
+	e.version, ack = s.log(event)
+	sh.set(key, e)
+	sh.evict(s.log)
+
+	return e.version, ack, nil
+}
+
+func (s *Store) Delete(key string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	sh := s.shard(key)
+
+	sh.Lock()
+	sh.remove(key)
+	_, ack := s.log(Event{Type: EventDelete, Key: key})
+	sh.Unlock()
 
-	s.data[key] = value
-	s.tl.WriteEvent(EventPut, key, value)
+	return s.wait(ack)
 }
 
-func (s *Store) Delete(key string) {
-	s.Lock()
-	defer s.Unlock()
+func (s *Store) Clear() error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	s.lockAll()
+
+	for _, sh := range s.shards {
+		sh.clear()
+	}
+
+	_, ack := s.log(Event{Type: EventClear})
+	s.unlockAll()
 
-	delete(s.data, key)
-	s.tl.WriteEvent(EventDelete, key, "")
+	return s.wait(ack)
 }
 
-func (s *Store) Clear() {
-	s.Lock()
-	defer s.Unlock()
+func (s *Store) reap() {
+	if s.follower.Load() {
+		return
+	}
+
+	now := time.Now().UnixNano()
 
-	clear(s.data)
-	s.tl.WriteEvent(EventClear, "", "")
+	for _, sh := range s.shards {
+		sh.reap(s.log, now)
+	}
+}
+
+// StartReaper runs background removing of expired keys every interval,
+// it is stopped by Shutdown
+func (s *Store) StartReaper(interval time.Duration) {
+	s.every(interval, s.reap)
+}
+
+// every runs f in background every interval until Shutdown
+func (s *Store) every(interval time.Duration, f func()) {
+	if s.stop == nil {
+		s.stop = make(chan struct{})
+	}
+
+	s.background.Add(1)
+
+	go func() {
+		defer s.background.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				f()
+			}
+		}
+	}()
+}
+
+func (s *Store) Shutdown(ctx context.Context) error {
+	if s.stop == nil {
+		return nil
+	}
+
+	close(s.stop)
+
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("shutdown store was cancelled: %w", ctx.Err())
+	case <-done:
+		return nil
+	}
+}
+
+// apply changes data according to the event, ID of the event becomes version
+// of changed keys. Caller should hold locks of all shards the event touches
+func (s *Store) apply(e Event, now int64) {
+	switch e.Type {
+	case EventPut:
+		s.shard(e.Key).set(e.Key, entry{value: e.Value, contentType: e.ContentType, version: e.ID})
+	case EventPutWithTTL, EventIncrement:
+		//put overwrites previous value even if it is already expired
+		if e.Deadline != 0 && e.Deadline <= now {
+			s.shard(e.Key).remove(e.Key)
+			return
+		}
+
+		s.shard(e.Key).set(e.Key, entry{value: e.Value, contentType: e.ContentType, deadline: e.Deadline, version: e.ID})
+	case EventDelete, EventExpire, EventEvict:
+		s.shard(e.Key).remove(e.Key)
+	case EventClear:
+		for _, sh := range s.shards {
+			sh.clear()
+		}
+	case EventSnapshot:
+		//it only carries ID of the last event included in the snapshot
+	case EventTxn:
+		for _, op := range e.Batch {
+			op.ID = e.ID
+			s.apply(op, now)
+		}
+	default:
+		if isCollectionEvent(e.Type) {
+			//changes those failed were never logged
+			_, _ = s.shard(e.Key).mutate(e, now)
+		}
+	}
 }
 
 func (s *Store) Restore() error {
+	return s.restoreTo(Point{})
+}
+
+// restoreTo applies events up to the point, later events are read but
+// not applied, so IDs of new events still go after all logged ones
+func (s *Store) restoreTo(p Point) error {
 	var err error
 
-	s.Lock()
-	defer s.Unlock()
+	s.lockAll()
+	defer s.unlockAll()
 
 	events, errs := s.tl.ReadEvents()
 	ok, event := true, Event{}
+	now := time.Now().UnixNano()
+	reached, compacted := false, false
 
 	for ok && err == nil {
 		select {
 		case err, ok = <-errs:
 		case event, ok = <-events:
-			switch event.Type {
-			case EventPut:
-				s.data[event.Key] = event.Value
-			case EventDelete:
-				delete(s.data, event.Key)
-			case EventClear:
-				clear(s.data)
+			//closed channel gives zero event, that is delete of empty key
+			if !ok {
+				break
 			}
+
+			s.lastID = max(s.lastID, event.ID)
+
+			//history before snapshot is not kept
+			if event.Type == EventSnapshot && p.before(event) {
+				compacted = true
+			}
+
+			reached = reached || p.before(event)
+			if !reached {
+				s.apply(event, now)
+			}
+		}
+	}
+
+	if err == nil && compacted {
+		err = ErrorHistoryCompacted
+	}
+
+	//limits could be decreased since last start, follower gets evictions of the leader
+	if err == nil && !s.follower.Load() {
+		for _, sh := range s.shards {
+			sh.evict(s.log)
 		}
 	}
 
-	fmt.Println("store", s.data)
+	keys := 0
+	for _, sh := range s.shards {
+		keys += len(sh.data)
+	}
+
+	fmt.Println("store restored", keys, "keys")
 
 	return err
 }
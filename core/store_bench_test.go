@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) WriteEvent(Event) <-chan error {
+	return Logged()
+}
+
+func (nopLogger) ReadEvents() (<-chan Event, <-chan error) {
+	events := make(chan Event)
+	errs := make(chan error)
+
+	close(events)
+	close(errs)
+
+	return events, errs
+}
+
+func (nopLogger) Start() <-chan error { return make(chan error) }
+
+func (nopLogger) Shutdown(context.Context) error { return nil }
+
+// BenchmarkStore compares single shard layout, that is equal to the store with
+// one global lock, with sharded layouts under mixed parallel load
+func BenchmarkStore(b *testing.B) {
+	const keys = 1 << 14
+
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = "key " + strconv.Itoa(i)
+	}
+
+	for _, shards := range []int{1, DefaultShards, 4 * DefaultShards} {
+		for _, readPercent := range []int{90, 50, 10} {
+			b.Run(fmt.Sprintf("shards=%d/reads=%d%%", shards, readPercent), func(b *testing.B) {
+				s := NewShardedStore(nopLogger{}, shards)
+				for _, key := range names {
+					s.Put(key, []byte(key))
+				}
+
+				b.ResetTimer()
+
+				b.RunParallel(func(pb *testing.PB) {
+					r := rand.New(rand.NewSource(rand.Int63()))
+
+					for pb.Next() {
+						key := names[r.Intn(keys)]
+
+						if r.Intn(100) < readPercent {
+							_, _ = s.Get(key)
+						} else {
+							s.Put(key, []byte(key))
+						}
+					}
+				})
+			})
+		}
+	}
+}
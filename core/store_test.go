@@ -0,0 +1,712 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memLogger keeps events in memory, so store written with it can be
+// restored by another store with the same logger
+type memLogger struct {
+	mu     sync.Mutex
+	events []Event
+	//fail is returned by WriteEvent instead of writing the event
+	fail error
+}
+
+func (l *memLogger) WriteEvent(e Event) <-chan error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.fail != nil {
+		ack := make(chan error, 1)
+		ack <- l.fail
+		return ack
+	}
+
+	l.events = append(l.events, e)
+
+	return Logged()
+}
+
+func (l *memLogger) ReadEvents() (<-chan Event, <-chan error) {
+	l.mu.Lock()
+	logged := append([]Event(nil), l.events...)
+	l.mu.Unlock()
+
+	events := make(chan Event)
+	errs := make(chan error)
+
+	//like FileLogger errors are closed after all events are read
+	go func() {
+		defer close(errs)
+		defer close(events)
+
+		for _, e := range logged {
+			events <- e
+		}
+	}()
+
+	return events, errs
+}
+
+// Compact replaces history by the state, it is called in order with WriteEvent
+func (l *memLogger) Compact(state []Event) <-chan error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = slices.Clone(state)
+
+	done := make(chan error, 1)
+	done <- nil
+
+	return done
+}
+
+func (l *memLogger) Check() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.fail
+}
+
+func (l *memLogger) LogSize() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int64(len(l.events))
+}
+
+func (l *memLogger) Start() <-chan error {
+	return make(chan error)
+}
+
+func (l *memLogger) Shutdown(context.Context) error {
+	return nil
+}
+
+func (l *memLogger) types() []EventType {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	types := make([]EventType, len(l.events))
+	for i, e := range l.events {
+		types[i] = e.Type
+	}
+
+	return types
+}
+
+func checkValue(t *testing.T, s *Store, key string, want string) {
+	t.Helper()
+
+	got, err := s.Get(key)
+	if err != nil {
+		t.Fatalf("get %q: unexpected error %v", key, err)
+	}
+	if string(got) != want {
+		t.Fatalf("get %q: got %q, want %q", key, got, want)
+	}
+}
+
+func checkNoSuchKey(t *testing.T, s *Store, key string) {
+	t.Helper()
+
+	if _, err := s.Get(key); !errors.Is(err, ErrorNoSuchKey) {
+		t.Fatalf("get %q: got error %v, want %v", key, err, ErrorNoSuchKey)
+	}
+}
+
+func TestPutWithTTL(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	if _, err := s.PutWithTTL("key", []byte("value"), 0); !errors.Is(err, ErrorInvalidTTL) {
+		t.Fatalf("got error %v, want %v", err, ErrorInvalidTTL)
+	}
+
+	if _, err := s.PutWithTTL("key", []byte("value"), 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	_, ttl, err := s.GetWithTTL("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > 50*time.Millisecond {
+		t.Fatalf("got ttl %v, want in (0, 50ms]", ttl)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	checkNoSuchKey(t, s, "key")
+
+	if types := tl.types(); types[len(types)-1] != EventExpire {
+		t.Fatalf("expiration was not logged, events: %v", types)
+	}
+}
+
+func TestPutOverwritesTTL(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	if _, err := s.PutWithTTL("key", []byte("old"), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	s.Put("key", []byte("new"))
+
+	time.Sleep(30 * time.Millisecond)
+	s.reap()
+
+	checkValue(t, s, "key", "new")
+}
+
+func TestReaper(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+	s.StartReaper(10 * time.Millisecond)
+
+	if _, err := s.PutWithTTL("key", []byte("value"), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	sh := s.shard("key")
+	sh.RLock()
+	_, exists := sh.data["key"]
+	sh.RUnlock()
+
+	if exists {
+		t.Fatal("expired key was not removed by reaper")
+	}
+	if types := tl.types(); types[len(types)-1] != EventExpire {
+		t.Fatalf("expiration was not logged, events: %v", types)
+	}
+}
+
+func TestRestoreDoesNotResurrectExpired(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	s.Put("expired", []byte("old"))
+	if _, err := s.PutWithTTL("expired", []byte("value"), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PutWithTTL("alive", []byte("value"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	restored := NewStore(tl)
+	if err := restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkNoSuchKey(t, restored, "expired")
+	checkValue(t, restored, "alive", "value")
+
+	if _, ttl, _ := restored.GetWithTTL("alive"); ttl == 0 {
+		t.Fatal("restored key lost its ttl")
+	}
+}
+
+// fifoPolicy evicts keys in order they were added
+type fifoPolicy struct {
+	keys []string
+}
+
+func newFifoPolicy() EvictionPolicy {
+	return &fifoPolicy{}
+}
+
+func (p *fifoPolicy) Add(key string, _ int64) {
+	p.Remove(key)
+	p.keys = append(p.keys, key)
+}
+
+func (p *fifoPolicy) Touch(string) {}
+
+func (p *fifoPolicy) Remove(key string) {
+	p.keys = slices.DeleteFunc(p.keys, func(k string) bool { return k == key })
+}
+
+func (p *fifoPolicy) Clear() {
+	p.keys = nil
+}
+
+func (p *fifoPolicy) Victim() (string, bool) {
+	if len(p.keys) == 0 {
+		return "", false
+	}
+
+	return p.keys[0], true
+}
+
+func TestEvictionByKeys(t *testing.T) {
+	tl := &memLogger{}
+	s := NewShardedStore(tl, 1).WithEviction(newFifoPolicy, 2, 0)
+
+	s.Put("a", []byte("1"))
+	s.Put("b", []byte("2"))
+	s.Put("c", []byte("3"))
+
+	checkNoSuchKey(t, s, "a")
+	checkValue(t, s, "b", "2")
+	checkValue(t, s, "c", "3")
+
+	if types := tl.types(); types[len(types)-1] != EventEvict {
+		t.Fatalf("eviction was not logged, events: %v", types)
+	}
+
+	restored := NewStore(tl)
+	if err := restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkNoSuchKey(t, restored, "a")
+	checkValue(t, restored, "b", "2")
+	checkValue(t, restored, "c", "3")
+}
+
+func TestEvictionByBytes(t *testing.T) {
+	s := NewShardedStore(&memLogger{}, 1).WithEviction(newFifoPolicy, 0, 10)
+
+	s.Put("a", []byte("1234"))
+	s.Put("b", []byte("1234"))
+	s.Put("a", []byte("12"))
+	checkValue(t, s, "b", "1234")
+
+	s.Put("c", []byte("1234"))
+	checkNoSuchKey(t, s, "b")
+	checkValue(t, s, "a", "12")
+	checkValue(t, s, "c", "1234")
+
+	s.Delete("c")
+	if size := s.shards[0].bytes; size != 3 {
+		t.Fatalf("got size %d, want 3", size)
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	if err := CheckLimits(10, 0, DefaultShards); !errors.Is(err, ErrorLimitTooSmall) {
+		t.Fatalf("got error %v of 10 keys, want %v", err, ErrorLimitTooSmall)
+	}
+	if err := CheckLimits(0, 8, DefaultShards); !errors.Is(err, ErrorLimitTooSmall) {
+		t.Fatalf("got error %v of 8 bytes, want %v", err, ErrorLimitTooSmall)
+	}
+	if err := CheckLimits(DefaultShards, 1<<20, DefaultShards); err != nil {
+		t.Fatal(err)
+	}
+
+	//store never keeps more keys than the limit
+	s := NewShardedStore(&memLogger{}, 4).WithEviction(newFifoPolicy, 10, 0)
+	for i := range 100 {
+		s.Put(strconv.Itoa(i), []byte("value"))
+	}
+
+	keys := 0
+	for _, sh := range s.shards {
+		keys += len(sh.data)
+	}
+	if keys > 10 {
+		t.Fatalf("got %d keys, limit is 10", keys)
+	}
+}
+
+func TestEvictionTooLarge(t *testing.T) {
+	tl := &memLogger{}
+	s := NewShardedStore(tl, 1).WithEviction(newFifoPolicy, 0, 10)
+
+	s.Put("a", []byte("1234"))
+
+	//key with value over the limit would evict everything and then itself
+	if _, err := s.Put("big", []byte("12345678")); !errors.Is(err, ErrorTooLarge) {
+		t.Fatalf("got error %v, want %v", err, ErrorTooLarge)
+	}
+	if _, err := s.Begin().Put("big", []byte("12345678")).Commit(); !errors.Is(err, ErrorTooLarge) {
+		t.Fatalf("got error %v of transaction, want %v", err, ErrorTooLarge)
+	}
+
+	checkValue(t, s, "a", "1234")
+	checkNoSuchKey(t, s, "big")
+
+	if types := tl.types(); !slices.Equal(types, []EventType{EventPut}) {
+		t.Fatalf("got events %v, want only put of a", types)
+	}
+}
+
+func TestRestoreAppliesDecreasedLimits(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	s.Put("a", []byte("1"))
+	s.Put("b", []byte("2"))
+
+	restored := NewShardedStore(tl, 1).WithEviction(newFifoPolicy, 1, 0)
+	if err := restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkNoSuchKey(t, restored, "a")
+	checkValue(t, restored, "b", "2")
+}
+
+func TestShardedStore(t *testing.T) {
+	tl := &memLogger{}
+	s := NewShardedStore(tl, 4)
+
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key %d", i)
+		s.Put(keys[i], []byte(keys[i]))
+	}
+
+	for _, sh := range s.shards {
+		if len(sh.data) == 0 {
+			t.Fatal("keys are not distributed between shards")
+		}
+	}
+
+	restored := NewShardedStore(tl, 3)
+	if err := restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range keys {
+		checkValue(t, restored, key, key)
+	}
+
+	restored.Clear()
+
+	for _, key := range keys {
+		checkNoSuchKey(t, restored, key)
+	}
+}
+
+func TestConcurrentWritesAndClearsReplayTheSame(t *testing.T) {
+	tl := &memLogger{}
+	s := NewShardedStore(tl, 8)
+
+	var wg sync.WaitGroup
+
+	//events from different shards interleave in the log, but replaying it
+	//should give exactly the same contents as the store has in memory
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 500; i++ {
+				key := strconv.Itoa(i % 50)
+
+				switch {
+				case i%97 == 0:
+					s.Clear()
+				case i%7 == 0:
+					s.Delete(key)
+				default:
+					s.Put(key, []byte(strconv.Itoa(w*1000+i)))
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	restored := NewShardedStore(tl, 8)
+	if err := restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 50; i++ {
+		key := strconv.Itoa(i)
+
+		want, wantErr := s.Get(key)
+		got, gotErr := restored.Get(key)
+
+		if !bytes.Equal(want, got) || !errors.Is(gotErr, wantErr) {
+			t.Fatalf("key %q: restored %q (%v), want %q (%v)", key, got, gotErr, want, wantErr)
+		}
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	v1, _ := s.Put("key", []byte("first"))
+	v2, err := s.CompareAndSwap("key", v1, []byte("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v2 <= v1 {
+		t.Fatalf("version did not grow: %d after %d", v2, v1)
+	}
+
+	if _, err = s.CompareAndSwap("key", v1, []byte("third")); !errors.Is(err, ErrorVersionMismatch) {
+		t.Fatalf("got error %v, want %v", err, ErrorVersionMismatch)
+	}
+	checkValue(t, s, "key", "second")
+
+	if _, err = s.CompareAndSwap("missing", 0, []byte("value")); !errors.Is(err, ErrorVersionMismatch) {
+		t.Fatalf("swap of missing key: got error %v, want %v", err, ErrorVersionMismatch)
+	}
+}
+
+func TestBinaryValue(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	value := []byte{0x89, 'P', 'N', 'G', 0, 0xff, '\n'}
+
+	if _, err := s.PutItem("thumbnail", Item{Value: value, ContentType: "image/png"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	//store keeps its own copy
+	value[0] = 0
+
+	restored := NewStore(tl)
+	if err := restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, store := range []*Store{s, restored} {
+		item, err := store.GetItem("thumbnail")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(item.Value, []byte{0x89, 'P', 'N', 'G', 0, 0xff, '\n'}) || item.ContentType != "image/png" {
+			t.Fatalf("got %v of type %q", item.Value, item.ContentType)
+		}
+	}
+}
+
+func TestPutIfAbsent(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	if _, err := s.PutIfAbsent("key", []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PutIfAbsent("key", []byte("second")); !errors.Is(err, ErrorKeyExists) {
+		t.Fatalf("got error %v, want %v", err, ErrorKeyExists)
+	}
+	checkValue(t, s, "key", "first")
+
+	if _, err := s.PutWithTTL("expiring", []byte("old"), 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := s.PutIfAbsent("expiring", []byte("new")); err != nil {
+		t.Fatalf("expired key should be absent: %v", err)
+	}
+}
+
+func TestDeleteIfVersion(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	version, _ := s.Put("key", []byte("value"))
+
+	if err := s.DeleteIfVersion("key", version+1); !errors.Is(err, ErrorVersionMismatch) {
+		t.Fatalf("got error %v, want %v", err, ErrorVersionMismatch)
+	}
+	checkValue(t, s, "key", "value")
+
+	if err := s.DeleteIfVersion("key", version); err != nil {
+		t.Fatal(err)
+	}
+	checkNoSuchKey(t, s, "key")
+
+	//nil condition is unconditional delete like nil condition of put
+	s.Put("key", []byte("value"))
+	if err := s.DeleteIf("key", nil); err != nil {
+		t.Fatal(err)
+	}
+	checkNoSuchKey(t, s, "key")
+}
+
+func TestVersionsSurviveRestore(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	s.Put("other", []byte("value"))
+	version, _ := s.Put("key", []byte("value"))
+
+	restored := NewStore(tl)
+	if err := restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := restored.GetItem("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Version != version {
+		t.Fatalf("got version %d, want %d", item.Version, version)
+	}
+
+	if next, _ := restored.Put("new", []byte("value")); next <= version {
+		t.Fatalf("version %d after restore is not greater than %d", next, version)
+	}
+}
+
+func TestTxn(t *testing.T) {
+	tl := &memLogger{}
+	s := NewShardedStore(tl, 4)
+
+	s.Put("deleted", []byte("value"))
+
+	txn := s.Begin().
+		Put("a", []byte("1")).
+		PutWithTTL("b", []byte("2"), time.Hour).
+		Delete("deleted")
+
+	version, err := txn.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"a", "b"} {
+		item, err := s.GetItem(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.Version != version {
+			t.Fatalf("key %q: got version %d, want version of transaction %d", key, item.Version, version)
+		}
+	}
+	checkNoSuchKey(t, s, "deleted")
+
+	if _, err = txn.Commit(); !errors.Is(err, ErrorTxnDone) {
+		t.Fatalf("got error %v, want %v", err, ErrorTxnDone)
+	}
+
+	if types := tl.types(); len(types) != 2 || types[1] != EventTxn {
+		t.Fatalf("transaction should be logged as one event, events: %v", types)
+	}
+
+	restored := NewShardedStore(tl, 2)
+	if err = restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkValue(t, restored, "a", "1")
+	checkValue(t, restored, "b", "2")
+	checkNoSuchKey(t, restored, "deleted")
+}
+
+func TestInvalidTxnAppliesNothing(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	_, err := s.Begin().
+		Put("a", []byte("1")).
+		PutWithTTL("b", []byte("2"), -time.Second).
+		Commit()
+
+	if !errors.Is(err, ErrorInvalidTTL) {
+		t.Fatalf("got error %v, want %v", err, ErrorInvalidTTL)
+	}
+
+	checkNoSuchKey(t, s, "a")
+	checkNoSuchKey(t, s, "b")
+}
+
+func TestIncrement(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	if got, err := s.Increment("counter", 5); err != nil || got != 5 {
+		t.Fatalf("increment of missing key: got %d (%v), want 5", got, err)
+	}
+	if got, err := s.Increment("counter", -7); err != nil || got != -2 {
+		t.Fatalf("got %d (%v), want -2", got, err)
+	}
+
+	s.Put("text", []byte("abc"))
+	if _, err := s.Increment("text", 1); !errors.Is(err, ErrorNotInteger) {
+		t.Fatalf("got error %v, want %v", err, ErrorNotInteger)
+	}
+
+	s.Put("max", []byte(strconv.FormatInt(math.MaxInt64, 10)))
+	if _, err := s.Increment("max", 1); !errors.Is(err, ErrorOverflow) {
+		t.Fatalf("got error %v, want %v", err, ErrorOverflow)
+	}
+
+	if _, err := s.PutWithTTL("expiring", []byte("10"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Increment("expiring", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ttl, _ := s.GetWithTTL("expiring"); ttl == 0 {
+		t.Fatal("increment dropped ttl of the key")
+	}
+
+	restored := NewStore(tl)
+	if err := restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkValue(t, restored, "counter", "-2")
+	checkValue(t, restored, "expiring", "11")
+
+	if _, ttl, _ := restored.GetWithTTL("expiring"); ttl == 0 {
+		t.Fatal("restored counter lost its ttl")
+	}
+}
+
+func TestConcurrentIncrement(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				if _, err := s.Increment("counter", 1); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	checkValue(t, s, "counter", "800")
+}
+
+func TestNotLogged(t *testing.T) {
+	broken := errors.New("disk is full")
+	s := NewStore(&memLogger{fail: broken})
+
+	//async view does not wait, so it never learns about the error
+	if _, err := s.Async().Put("key", []byte("async")); err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, s, "key", "async")
+
+	//change is applied even if it was not logged
+	if _, err := s.Put("key", []byte("value")); !errors.Is(err, ErrorNotLogged) || !errors.Is(err, broken) {
+		t.Fatalf("got error %v, want %v", err, ErrorNotLogged)
+	}
+	checkValue(t, s, "key", "value")
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"time"
+)
+
+var ErrorTxnDone = errors.New("transaction is already committed")
+
+// Txn queues changes those are applied all together by Commit,
+// they are written to the transaction logger as one event, so after restart
+// store has either all of them or none
+type Txn struct {
+	store *Store
+	ops   []Event
+	ttls  []time.Duration
+	err   error
+	done  bool
+}
+
+func (s *Store) Begin() *Txn {
+	return &Txn{store: s}
+}
+
+func (t *Txn) Put(key string, value []byte) *Txn {
+	t.checkSize(key, value)
+
+	t.ops = append(t.ops, Event{Type: EventPut, Key: key, Value: bytes.Clone(value)})
+	t.ttls = append(t.ttls, 0)
+	return t
+}
+
+// PutWithTTL queues put of the key with ttl, ttl is counted from the commit
+func (t *Txn) PutWithTTL(key string, value []byte, ttl time.Duration) *Txn {
+	if ttl <= 0 && t.err == nil {
+		t.err = ErrorInvalidTTL
+	}
+	t.checkSize(key, value)
+
+	t.ops = append(t.ops, Event{Type: EventPutWithTTL, Key: key, Value: bytes.Clone(value)})
+	t.ttls = append(t.ttls, ttl)
+	return t
+}
+
+// checkSize fails the transaction if the value could not fit the shard of the key
+func (t *Txn) checkSize(key string, value []byte) {
+	if t.err == nil && t.store.shard(key).tooLarge(len(key)+len(value)) {
+		t.err = ErrorTooLarge
+	}
+}
+
+func (t *Txn) Delete(key string) *Txn {
+	t.ops = append(t.ops, Event{Type: EventDelete, Key: key})
+	t.ttls = append(t.ttls, 0)
+	return t
+}
+
+// Commit applies queued changes under locks of all shards they touch and
+// returns ID of the transaction, it becomes version of every changed key.
+// Transaction could not be committed twice, if any queued change was invalid
+// nothing is applied
+func (t *Txn) Commit() (uint64, error) {
+	if t.done {
+		return 0, ErrorTxnDone
+	}
+
+	t.done = true
+
+	if t.err != nil {
+		return 0, t.err
+	}
+	if len(t.ops) == 0 {
+		return 0, nil
+	}
+
+	if err := t.store.writable(); err != nil {
+		return 0, err
+	}
+
+	id, ack := t.commit()
+
+	return id, t.store.wait(ack)
+}
+
+func (t *Txn) commit() (uint64, <-chan error) {
+	s := t.store
+
+	//shards are always locked in ascending order like in lockAll
+	indexes := make([]int, 0, len(t.ops))
+	for _, op := range t.ops {
+		indexes = append(indexes, s.shardIndex(op.Key))
+	}
+
+	slices.Sort(indexes)
+	indexes = slices.Compact(indexes)
+
+	for _, i := range indexes {
+		s.shards[i].Lock()
+		defer s.shards[i].Unlock()
+	}
+
+	now := time.Now()
+
+	for i := range t.ops {
+		if t.ttls[i] != 0 {
+			t.ops[i].Deadline = now.Add(t.ttls[i]).UnixNano()
+		}
+	}
+
+	var ack <-chan error
+
+	event := Event{Type: EventTxn, Batch: t.ops}
+	event.ID, ack = s.log(event)
+	s.apply(event, now.UnixNano())
+
+	for _, i := range indexes {
+		s.shards[i].evict(s.log)
+	}
+
+	return event.ID, ack
+}
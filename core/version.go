@@ -0,0 +1,111 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrorVersionMismatch = errors.New("version mismatch")
+var ErrorKeyExists = errors.New("key already exists")
+
+// Condition is checked under the lock against the current version of the key
+// before conditional write, exists is false for missing and expired keys
+type Condition func(version uint64, exists bool) error
+
+func IfVersion(expected uint64) Condition {
+	return func(version uint64, exists bool) error {
+		if !exists || version != expected {
+			return ErrorVersionMismatch
+		}
+
+		return nil
+	}
+}
+
+func IfAbsent() Condition {
+	return func(_ uint64, exists bool) error {
+		if exists {
+			return ErrorKeyExists
+		}
+
+		return nil
+	}
+}
+
+func IfExists() Condition {
+	return func(_ uint64, exists bool) error {
+		if !exists {
+			return ErrorNoSuchKey
+		}
+
+		return nil
+	}
+}
+
+// PutIf puts value if cond is satisfied and returns new version of the key,
+// zero ttl means that key never expires
+func (s *Store) PutIf(key string, value []byte, ttl time.Duration, cond Condition) (uint64, error) {
+	return s.PutItem(key, Item{Value: value, TTL: ttl}, cond)
+}
+
+// PutItem puts value with its media type and ttl if cond is satisfied, version
+// of the item is ignored, nil cond is always satisfied
+func (s *Store) PutItem(key string, item Item, cond Condition) (uint64, error) {
+	if item.TTL < 0 {
+		return 0, ErrorInvalidTTL
+	}
+
+	return s.put(key, item, cond)
+}
+
+func (s *Store) CompareAndSwap(key string, expectedVersion uint64, value []byte) (uint64, error) {
+	return s.put(key, Item{Value: value}, IfVersion(expectedVersion))
+}
+
+func (s *Store) PutIfAbsent(key string, value []byte) (uint64, error) {
+	return s.put(key, Item{Value: value}, IfAbsent())
+}
+
+// DeleteIf deletes key if cond is satisfied, it does nothing if key does not exist
+// and cond allows it, nil cond is always satisfied
+func (s *Store) DeleteIf(key string, cond Condition) error {
+	ack, err := s.deleteIfLocked(key, cond)
+	if err != nil {
+		return err
+	}
+
+	return s.wait(ack)
+}
+
+func (s *Store) deleteIfLocked(key string, cond Condition) (<-chan error, error) {
+	if err := s.writable(); err != nil {
+		return nil, err
+	}
+
+	sh := s.shard(key)
+
+	sh.Lock()
+	defer sh.Unlock()
+
+	e, exists := sh.data[key]
+	exists = exists && !e.expired(time.Now().UnixNano())
+
+	if cond != nil {
+		if err := cond(e.version, exists); err != nil {
+			return nil, err
+		}
+	}
+
+	if !exists {
+		return nil, nil
+	}
+
+	sh.remove(key)
+	_, ack := s.log(Event{Type: EventDelete, Key: key})
+
+	return ack, nil
+}
+
+func (s *Store) DeleteIfVersion(key string, version uint64) error {
+	return s.DeleteIf(key, IfVersion(version))
+}
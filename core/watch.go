@@ -0,0 +1,124 @@
+package core
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrorWatcherTooSlow = errors.New("watcher did not keep up with changes and was disconnected")
+var ErrorStateReplaced = errors.New("state was replaced by snapshot of the leader")
+
+// Watcher receives events those change watched keys in order of their IDs.
+// Writers never wait for watchers, when buffer of the watcher is full it is
+// disconnected, its channel is closed and Err returns ErrorWatcherTooSlow,
+// so the subscriber could read actual state and watch again
+type Watcher struct {
+	store  *Store
+	key    string
+	prefix bool
+	//all gets every event as it is logged, transaction as one event
+	all    bool
+	events chan Event
+	err    error
+}
+
+// Watch subscribes to changes of the key or of all keys with this prefix,
+// buffer is the number of events those could wait for the subscriber.
+// Clear is sent to every watcher, transaction is sent as its separate changes
+// with ID of the transaction
+func (s *Store) Watch(key string, prefix bool, buffer int) *Watcher {
+	w := &Watcher{
+		store:  s,
+		key:    key,
+		prefix: prefix,
+		events: make(chan Event, max(buffer, 1)),
+	}
+
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+
+	s.addWatcher(w)
+
+	return w
+}
+
+// addWatcher should be called under seqMu
+func (s *Store) addWatcher(w *Watcher) {
+	if s.watchers == nil {
+		s.watchers = make(map[*Watcher]struct{})
+	}
+
+	s.watchers[w] = struct{}{}
+}
+
+// Events is closed when watcher is closed or disconnected
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Err reports why the watcher was disconnected, it is nil if watcher was closed
+func (w *Watcher) Err() error {
+	return w.err
+}
+
+func (w *Watcher) Close() {
+	w.store.seqMu.Lock()
+	defer w.store.seqMu.Unlock()
+
+	w.store.unwatch(w, nil)
+}
+
+func (w *Watcher) matches(e Event) bool {
+	if e.Type == EventClear {
+		return true
+	}
+	if w.prefix {
+		return strings.HasPrefix(e.Key, w.key)
+	}
+
+	return e.Key == w.key
+}
+
+// unwatch should be called under seqMu
+func (s *Store) unwatch(w *Watcher, err error) {
+	if _, ok := s.watchers[w]; !ok {
+		return
+	}
+
+	delete(s.watchers, w)
+	w.err = err
+	close(w.events)
+}
+
+// notify is called by log under seqMu, so watchers get events in order of IDs
+func (s *Store) notify(e Event) {
+	for w := range s.watchers {
+		switch {
+		case w.all:
+			s.send(w, e)
+		case e.Type == EventSnapshot:
+			//changes of keys those were replaced are not known
+			s.unwatch(w, ErrorStateReplaced)
+		case e.Type == EventTxn:
+			for _, op := range e.Batch {
+				op.ID = e.ID
+				if w.matches(op) && !s.send(w, op) {
+					break
+				}
+			}
+		case w.matches(e):
+			s.send(w, e)
+		}
+	}
+}
+
+// send returns false if the watcher was disconnected, because its buffer is full
+func (s *Store) send(w *Watcher, e Event) bool {
+	select {
+	case w.events <- e:
+		return true
+	default:
+		s.unwatch(w, ErrorWatcherTooSlow)
+		return false
+	}
+}
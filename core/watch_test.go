@@ -0,0 +1,116 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// subscription is a watcher or a feed
+type subscription interface {
+	Events() <-chan Event
+	Err() error
+}
+
+func receive(t *testing.T, sub subscription, eventType EventType, key string, id uint64) {
+	t.Helper()
+
+	select {
+	case e, ok := <-sub.Events():
+		if !ok {
+			t.Fatalf("subscription was closed: %v", sub.Err())
+		}
+		if e.Type != eventType || e.Key != key || e.ID != id {
+			t.Fatalf("got event %d %q with ID %d, want %d %q with ID %d", e.Type, e.Key, e.ID, eventType, key, id)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no event, want %d %q with ID %d", eventType, key, id)
+	}
+}
+
+func nothingReceived(t *testing.T, w *Watcher) {
+	t.Helper()
+
+	select {
+	case e := <-w.Events():
+		t.Fatalf("got unexpected event %v", e)
+	default:
+	}
+}
+
+func TestWatch(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	key := s.Watch("config", false, 10)
+	defer key.Close()
+
+	prefix := s.Watch("user:", true, 10)
+	defer prefix.Close()
+
+	v1, _ := s.Put("config", []byte("a"))
+	v2, _ := s.Put("user:1", []byte("b"))
+	s.Put("other", []byte("c"))
+	s.Delete("config")
+
+	receive(t, key, EventPut, "config", v1)
+	receive(t, key, EventDelete, "config", v1+3)
+	nothingReceived(t, key)
+
+	receive(t, prefix, EventPut, "user:1", v2)
+	nothingReceived(t, prefix)
+
+	txn := s.Begin()
+	txn.Put("user:2", []byte("d"))
+	txn.Put("config", []byte("e"))
+
+	id, err := txn.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receive(t, key, EventPut, "config", id)
+	receive(t, prefix, EventPut, "user:2", id)
+
+	s.Clear()
+
+	receive(t, key, EventClear, "", id+1)
+	receive(t, prefix, EventClear, "", id+1)
+}
+
+func TestWatchSlowConsumer(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	slow := s.Watch("key", false, 2)
+	fast := s.Watch("key", false, 10)
+	defer fast.Close()
+
+	for i := 0; i < 3; i++ {
+		s.Put("key", []byte("value"))
+	}
+
+	//buffered events are still delivered before channel is closed
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+
+	if received != 2 {
+		t.Fatalf("got %d events, want 2", received)
+	}
+	if !errors.Is(slow.Err(), ErrorWatcherTooSlow) {
+		t.Fatalf("got error %v, want %v", slow.Err(), ErrorWatcherTooSlow)
+	}
+
+	//other watchers are not affected
+	if len(fast.Events()) != 3 {
+		t.Fatalf("got %d events, want 3", len(fast.Events()))
+	}
+
+	//closed watcher does not receive anything and does not block writers
+	fast.Close()
+	s.Put("key", []byte("value"))
+
+	if fast.Err() != nil {
+		t.Fatalf("got error %v for closed watcher", fast.Err())
+	}
+}
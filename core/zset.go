@@ -0,0 +1,156 @@
+package core
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrorInvalidScore = errors.New("score should be a finite number")
+var ErrorNoSuchMember = errors.New("no such member")
+
+// scoreSize is size of score of every member of sorted set
+const scoreSize = 8
+
+type ZMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// zset is sorted set, map gives score of member and skip list keeps order
+type zset struct {
+	list   *skipList
+	scores map[string]float64
+}
+
+func newZSet() *zset {
+	return &zset{list: newSkipList(), scores: make(map[string]float64)}
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'g', -1, 64)
+}
+
+func (z *zset) add(member string, score float64) {
+	if old, exists := z.scores[member]; exists {
+		z.list.delete(ZMember{Member: member, Score: old})
+	}
+
+	z.scores[member] = score
+	z.list.insert(ZMember{Member: member, Score: score})
+}
+
+func (z *zset) remove(member string) {
+	z.list.delete(ZMember{Member: member, Score: z.scores[member]})
+	delete(z.scores, member)
+}
+
+// ZAdd sets score of the member and reports whether member was not there before,
+// sorted set is created if key does not exist
+func (s *Store) ZAdd(key string, member string, score float64) (bool, error) {
+	old, _, err := s.mutate(Event{Type: EventZSetAdd, Key: key, Field: member, Value: []byte(formatScore(score))})
+	if errors.Is(err, errNotChanged) {
+		return false, nil
+	}
+
+	//previous score is returned only if member was already there
+	return err == nil && old == "", err
+}
+
+// ZRemove removes member from the sorted set and reports whether it was there,
+// key is deleted with its last member
+func (s *Store) ZRemove(key string, member string) (bool, error) {
+	_, _, err := s.mutate(Event{Type: EventZSetRemove, Key: key, Value: []byte(member)})
+	if errors.Is(err, errNotChanged) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// ZPopMin removes and returns member with the lowest score,
+// it is logged as removing of that member
+func (s *Store) ZPopMin(key string) (ZMember, error) {
+	sh := s.shard(key)
+
+	sh.Lock()
+	m, ack, err := s.popMinLocked(sh, key)
+	sh.Unlock()
+
+	if err != nil {
+		return ZMember{}, err
+	}
+
+	return m, s.wait(ack)
+}
+
+func (s *Store) popMinLocked(sh *shard, key string) (ZMember, <-chan error, error) {
+	c, ok := sh.data[key]
+	if !ok || c.expired(time.Now().UnixNano()) {
+		return ZMember{}, nil, ErrorNoSuchKey
+	}
+	if c.kind != kindZSet {
+		return ZMember{}, nil, ErrorWrongType
+	}
+
+	m := c.zset.list.first().ZMember
+
+	_, _, ack, err := s.mutateLocked(sh, Event{Type: EventZSetRemove, Key: key, Value: []byte(m.Member)})
+	if err != nil {
+		return ZMember{}, nil, err
+	}
+
+	return m, ack, nil
+}
+
+func (s *Store) ZScore(key string, member string) (score float64, err error) {
+	exists := false
+
+	err = s.read(key, kindZSet, func(c entry) {
+		score, exists = c.zset.scores[member]
+	})
+
+	if err == nil && !exists {
+		err = ErrorNoSuchMember
+	}
+
+	return score, err
+}
+
+// ZRank returns 0-based position of the member in order of scores
+func (s *Store) ZRank(key string, member string) (rank int, err error) {
+	err = s.read(key, kindZSet, func(c entry) {
+		score, ok := c.zset.scores[member]
+		if !ok {
+			rank = -1
+			return
+		}
+
+		rank = c.zset.list.rank(ZMember{Member: member, Score: score})
+	})
+
+	if err == nil && rank < 0 {
+		err = ErrorNoSuchMember
+	}
+
+	return rank, err
+}
+
+// ZRange returns members from start to stop rank inclusive ordered by score,
+// negative ranks are counted from the end, so 0 and -1 give whole set
+func (s *Store) ZRange(key string, start int, stop int) (members []ZMember, err error) {
+	err = s.read(key, kindZSet, func(c entry) {
+		members = c.zset.list.rangeByRank(start, stop)
+	})
+
+	return members, err
+}
+
+// ZRangeByScore returns members with min <= score <= max ordered by score
+func (s *Store) ZRangeByScore(key string, min float64, max float64) (members []ZMember, err error) {
+	err = s.read(key, kindZSet, func(c entry) {
+		members = c.zset.list.rangeByScore(min, max)
+	})
+
+	return members, err
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestSkipList(t *testing.T) {
+	l := newSkipList()
+	var want []ZMember
+
+	sorted := func() {
+		slices.SortFunc(want, func(a, b ZMember) int {
+			if a.less(b) {
+				return -1
+			}
+			if b.less(a) {
+				return 1
+			}
+			return 0
+		})
+	}
+
+	//few distinct scores make members with equal scores ordered by name
+	for i := 0; i < 1000; i++ {
+		m := ZMember{Member: strconv.Itoa(i), Score: float64(rand.Intn(50))}
+		l.insert(m)
+		want = append(want, m)
+	}
+
+	sorted()
+
+	for i := 0; i < 300; i++ {
+		j := rand.Intn(len(want))
+		if !l.delete(want[j]) {
+			t.Fatalf("member %v was not deleted", want[j])
+		}
+		want = slices.Delete(want, j, j+1)
+	}
+
+	if l.delete(ZMember{Member: "absent"}) {
+		t.Fatal("absent member was deleted")
+	}
+	if l.length != len(want) {
+		t.Fatalf("got length %d, want %d", l.length, len(want))
+	}
+
+	for i, m := range want {
+		if rank := l.rank(m); rank != i {
+			t.Fatalf("got rank %d of %v, want %d", rank, m, i)
+		}
+		if node := l.byRank(i); node == nil || node.ZMember != m {
+			t.Fatalf("got %v at rank %d, want %v", node, i, m)
+		}
+	}
+
+	if got := l.rangeByRank(0, -1); !slices.Equal(got, want) {
+		t.Fatal("range of whole list differs from sorted members")
+	}
+	if got := l.rangeByRank(-10, -5); !slices.Equal(got, want[len(want)-10:len(want)-4]) {
+		t.Fatalf("got %v, want %v", got, want[len(want)-10:len(want)-4])
+	}
+
+	var inRange []ZMember
+	for _, m := range want {
+		if m.Score >= 10 && m.Score <= 20 {
+			inRange = append(inRange, m)
+		}
+	}
+
+	if got := l.rangeByScore(10, 20); !slices.Equal(got, inRange) {
+		t.Fatalf("got %d members in range, want %d", len(got), len(inRange))
+	}
+	if got := l.rangeByScore(100, math.Inf(1)); len(got) != 0 {
+		t.Fatalf("got %v, want empty range", got)
+	}
+}
+
+func TestZSet(t *testing.T) {
+	s := NewStore(&memLogger{})
+
+	for member, score := range map[string]float64{"a": 3, "b": 1, "c": 2} {
+		if added, err := s.ZAdd("zset", member, score); err != nil || !added {
+			t.Fatalf("got %v (%v), want added", added, err)
+		}
+	}
+
+	//update of score moves member
+	if added, err := s.ZAdd("zset", "a", 0.5); err != nil || added {
+		t.Fatalf("got %v (%v), want updated", added, err)
+	}
+	if _, err := s.ZAdd("zset", "d", math.NaN()); !errors.Is(err, ErrorInvalidScore) {
+		t.Fatalf("got error %v, want %v", err, ErrorInvalidScore)
+	}
+
+	want := []ZMember{{"a", 0.5}, {"b", 1}, {"c", 2}}
+	if members, err := s.ZRange("zset", 0, -1); err != nil || !slices.Equal(members, want) {
+		t.Fatalf("got %v (%v), want %v", members, err, want)
+	}
+	if members, err := s.ZRangeByScore("zset", 1, math.Inf(1)); err != nil || !slices.Equal(members, want[1:]) {
+		t.Fatalf("got %v (%v), want %v", members, err, want[1:])
+	}
+
+	if rank, err := s.ZRank("zset", "c"); err != nil || rank != 2 {
+		t.Fatalf("got rank %d (%v), want 2", rank, err)
+	}
+	if _, err := s.ZRank("zset", "e"); !errors.Is(err, ErrorNoSuchMember) {
+		t.Fatalf("got error %v, want %v", err, ErrorNoSuchMember)
+	}
+	if score, err := s.ZScore("zset", "b"); err != nil || score != 1 {
+		t.Fatalf("got score %v (%v), want 1", score, err)
+	}
+
+	if m, err := s.ZPopMin("zset"); err != nil || m != want[0] {
+		t.Fatalf("got %v (%v), want %v", m, err, want[0])
+	}
+	if removed, err := s.ZRemove("zset", "b"); err != nil || !removed {
+		t.Fatalf("got %v (%v), want removed", removed, err)
+	}
+	if removed, err := s.ZRemove("zset", "b"); err != nil || removed {
+		t.Fatalf("got %v (%v), want not removed", removed, err)
+	}
+	if _, err := s.ZPopMin("zset"); err != nil {
+		t.Fatal(err)
+	}
+
+	//sorted set is deleted with its last member
+	if _, err := s.ZPopMin("zset"); !errors.Is(err, ErrorNoSuchKey) {
+		t.Fatalf("got error %v, want %v", err, ErrorNoSuchKey)
+	}
+}
+
+func TestRestoreZSet(t *testing.T) {
+	tl := &memLogger{}
+	s := NewStore(tl)
+
+	for i, score := range []float64{0.1, -7, 1e300, 0.1} {
+		if _, err := s.ZAdd("zset", strconv.Itoa(i), score); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.ZAdd("zset", "0", 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ZPopMin("zset"); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := s.ZRange("zset", 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewStore(tl)
+	if err := restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	if members, err := restored.ZRange("zset", 0, -1); err != nil || !slices.Equal(members, want) {
+		t.Fatalf("got %v (%v), want %v", members, err, want)
+	}
+}
@@ -0,0 +1,37 @@
+package eviction
+
+// LFU evicts key with the lowest number of reads and writes,
+// the least recently used one among keys with equal frequency
+type LFU struct {
+	keys keyHeap
+}
+
+func NewLFU() *LFU {
+	return &LFU{keys: newKeyHeap()}
+}
+
+func increment(old int64) int64 {
+	return old + 1
+}
+
+func (p *LFU) Add(key string, _ int64) {
+	p.keys.update(key, increment)
+}
+
+func (p *LFU) Touch(key string) {
+	if _, ok := p.keys.keys[key]; ok {
+		p.keys.update(key, increment)
+	}
+}
+
+func (p *LFU) Remove(key string) {
+	p.keys.remove(key)
+}
+
+func (p *LFU) Clear() {
+	p.keys.clear()
+}
+
+func (p *LFU) Victim() (string, bool) {
+	return p.keys.min()
+}
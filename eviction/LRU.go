@@ -0,0 +1,55 @@
+package eviction
+
+import (
+	"container/list"
+)
+
+// LRU evicts key that was not read or written for the longest time
+type LRU struct {
+	//front is the most recently used key
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func NewLRU() *LRU {
+	return &LRU{
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+func (p *LRU) Add(key string, _ int64) {
+	if el, ok := p.elements[key]; ok {
+		p.order.MoveToFront(el)
+		return
+	}
+
+	p.elements[key] = p.order.PushFront(key)
+}
+
+func (p *LRU) Touch(key string) {
+	if el, ok := p.elements[key]; ok {
+		p.order.MoveToFront(el)
+	}
+}
+
+func (p *LRU) Remove(key string) {
+	if el, ok := p.elements[key]; ok {
+		p.order.Remove(el)
+		delete(p.elements, key)
+	}
+}
+
+func (p *LRU) Clear() {
+	p.order.Init()
+	clear(p.elements)
+}
+
+func (p *LRU) Victim() (string, bool) {
+	el := p.order.Back()
+	if el == nil {
+		return "", false
+	}
+
+	return el.Value.(string), true
+}
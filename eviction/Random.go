@@ -0,0 +1,36 @@
+package eviction
+
+import (
+	"cache/set"
+)
+
+// Random evicts uniformly random key
+type Random struct {
+	keys *set.Set
+}
+
+func NewRandom() *Random {
+	return &Random{keys: set.New()}
+}
+
+func (p *Random) Add(key string, _ int64) {
+	p.keys.Add(key)
+}
+
+func (p *Random) Touch(string) {}
+
+func (p *Random) Remove(key string) {
+	p.keys.Remove(key)
+}
+
+func (p *Random) Clear() {
+	p.keys.Clear()
+}
+
+func (p *Random) Victim() (string, bool) {
+	if p.keys.Len() == 0 {
+		return "", false
+	}
+
+	return p.keys.Random(), true
+}
@@ -0,0 +1,37 @@
+package eviction
+
+import (
+	"math"
+)
+
+// TTL evicts key with the nearest deadline, keys without ttl are
+// evicted only when there are no keys with ttl, the oldest written first
+type TTL struct {
+	keys keyHeap
+}
+
+func NewTTL() *TTL {
+	return &TTL{keys: newKeyHeap()}
+}
+
+func (p *TTL) Add(key string, deadline int64) {
+	if deadline == 0 {
+		deadline = math.MaxInt64
+	}
+
+	p.keys.update(key, func(int64) int64 { return deadline })
+}
+
+func (p *TTL) Touch(string) {}
+
+func (p *TTL) Remove(key string) {
+	p.keys.remove(key)
+}
+
+func (p *TTL) Clear() {
+	p.keys.clear()
+}
+
+func (p *TTL) Victim() (string, bool) {
+	return p.keys.min()
+}
@@ -0,0 +1,30 @@
+package eviction
+
+import (
+	"cache/core"
+	"fmt"
+)
+
+const (
+	RandomName = "random"
+	LRUName    = "lru"
+	LFUName    = "lfu"
+	TTLName    = "ttl"
+)
+
+// Factory returns constructor of the policy with given name,
+// store creates separate policy for each of its shards
+func Factory(name string) (func() core.EvictionPolicy, error) {
+	switch name {
+	case RandomName:
+		return func() core.EvictionPolicy { return NewRandom() }, nil
+	case LRUName:
+		return func() core.EvictionPolicy { return NewLRU() }, nil
+	case LFUName:
+		return func() core.EvictionPolicy { return NewLFU() }, nil
+	case TTLName:
+		return func() core.EvictionPolicy { return NewTTL() }, nil
+	default:
+		return nil, fmt.Errorf("unknown eviction policy %q", name)
+	}
+}
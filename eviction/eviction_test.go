@@ -0,0 +1,120 @@
+package eviction
+
+import (
+	"cache/core"
+	"testing"
+)
+
+func checkVictim(t *testing.T, p core.EvictionPolicy, want string) {
+	t.Helper()
+
+	got, ok := p.Victim()
+	if !ok {
+		t.Fatalf("no victim, want %q", want)
+	}
+	if got != want {
+		t.Fatalf("got victim %q, want %q", got, want)
+	}
+}
+
+func checkEmpty(t *testing.T, p core.EvictionPolicy) {
+	t.Helper()
+
+	if key, ok := p.Victim(); ok {
+		t.Fatalf("got victim %q from empty policy", key)
+	}
+}
+
+func TestFactory(t *testing.T) {
+	for _, name := range []string{RandomName, LRUName, LFUName, TTLName} {
+		newPolicy, err := Factory(name)
+		if err != nil {
+			t.Errorf("policy %q: %v", name, err)
+			continue
+		}
+
+		if newPolicy() == newPolicy() {
+			t.Errorf("policy %q: factory returns the same policy", name)
+		}
+	}
+
+	if _, err := Factory("fifo"); err == nil {
+		t.Error("unknown policy was created")
+	}
+}
+
+func TestRandom(t *testing.T) {
+	p := NewRandom()
+	checkEmpty(t, p)
+
+	p.Add("a", 0)
+	p.Add("b", 0)
+	p.Remove("a")
+	checkVictim(t, p, "b")
+
+	p.Clear()
+	checkEmpty(t, p)
+}
+
+func TestLRU(t *testing.T) {
+	p := NewLRU()
+	checkEmpty(t, p)
+
+	p.Add("a", 0)
+	p.Add("b", 0)
+	p.Add("c", 0)
+	checkVictim(t, p, "a")
+
+	p.Touch("a")
+	checkVictim(t, p, "b")
+
+	p.Add("b", 0)
+	checkVictim(t, p, "c")
+
+	p.Remove("c")
+	checkVictim(t, p, "a")
+
+	p.Clear()
+	checkEmpty(t, p)
+}
+
+func TestLFU(t *testing.T) {
+	p := NewLFU()
+	checkEmpty(t, p)
+
+	p.Add("a", 0)
+	p.Add("b", 0)
+	p.Touch("a")
+	checkVictim(t, p, "b")
+
+	p.Touch("b")
+	//equal frequencies, least recently used goes first
+	checkVictim(t, p, "a")
+
+	p.Touch("unknown")
+	p.Remove("a")
+	checkVictim(t, p, "b")
+
+	p.Clear()
+	checkEmpty(t, p)
+}
+
+func TestTTL(t *testing.T) {
+	p := NewTTL()
+	checkEmpty(t, p)
+
+	p.Add("forever", 0)
+	p.Add("late", 200)
+	p.Add("soon", 100)
+	checkVictim(t, p, "soon")
+
+	p.Add("soon", 0)
+	checkVictim(t, p, "late")
+
+	p.Remove("late")
+	//keys without ttl are evicted in write order
+	checkVictim(t, p, "forever")
+
+	p.Clear()
+	checkEmpty(t, p)
+}
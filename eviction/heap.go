@@ -0,0 +1,94 @@
+package eviction
+
+import (
+	"container/heap"
+)
+
+type item struct {
+	key      string
+	priority int64
+	//order of the last update, breaks ties between equal priorities
+	tick  uint64
+	index int
+}
+
+// keyHeap is an indexed min-heap of keys ordered by priority and then by tick,
+// it is shared by policies those evict key with the lowest priority
+type keyHeap struct {
+	items []*item
+	keys  map[string]*item
+	ticks uint64
+}
+
+func newKeyHeap() keyHeap {
+	return keyHeap{keys: make(map[string]*item)}
+}
+
+func (h *keyHeap) Len() int { return len(h.items) }
+
+func (h *keyHeap) Less(i, j int) bool {
+	if h.items[i].priority != h.items[j].priority {
+		return h.items[i].priority < h.items[j].priority
+	}
+
+	return h.items[i].tick < h.items[j].tick
+}
+
+func (h *keyHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *keyHeap) Push(x any) {
+	it := x.(*item)
+	it.index = len(h.items)
+	h.items = append(h.items, it)
+}
+
+func (h *keyHeap) Pop() any {
+	n := len(h.items)
+	it := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+
+	return it
+}
+
+// update sets priority of key with function of its previous priority,
+// previous priority of new keys is zero
+func (h *keyHeap) update(key string, priority func(old int64) int64) {
+	h.ticks++
+
+	if it, ok := h.keys[key]; ok {
+		it.priority = priority(it.priority)
+		it.tick = h.ticks
+		heap.Fix(h, it.index)
+		return
+	}
+
+	it := &item{key: key, priority: priority(0), tick: h.ticks}
+	h.keys[key] = it
+	heap.Push(h, it)
+}
+
+func (h *keyHeap) remove(key string) {
+	if it, ok := h.keys[key]; ok {
+		heap.Remove(h, it.index)
+		delete(h.keys, key)
+	}
+}
+
+func (h *keyHeap) clear() {
+	clear(h.items)
+	h.items = h.items[:0]
+	clear(h.keys)
+}
+
+func (h *keyHeap) min() (string, bool) {
+	if len(h.items) == 0 {
+		return "", false
+	}
+
+	return h.items[0].key, true
+}
@@ -0,0 +1,128 @@
+package frontend
+
+import (
+	"cache/core"
+	"cache/transaction"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// changesBuffer is bigger than watchBuffer, live changes wait in it
+// while history is read from the log
+const changesBuffer = 4096
+
+// Changes streams every logged change after "since" event ID as records of json log,
+// history is read from the log and live changes follow it. Stream is Server-Sent Events
+// if client accepts text/event-stream, so Last-Event-ID resumes it, otherwise it is
+// newline-delimited JSON and client resumes from ID of the last received change.
+// Last-Event-ID wins over "since", because EventSource reconnects by the URL of the
+// first request, so "since" of it is older than the last received change
+func (f *Rest) Changes(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	since := uint64(0)
+
+	if param := cmp.Or(r.Header.Get("Last-Event-ID"), r.URL.Query().Get("since")); param != "" {
+		var err error
+		if since, err = strconv.ParseUint(param, 10, 64); err != nil {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return
+		}
+	}
+
+	feed, err := f.store.Changes(since, changesBuffer)
+	if errors.Is(err, core.ErrorHistoryCompacted) {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer feed.Close()
+
+	sse := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(lastIDHeader, strconv.FormatUint(f.store.LastID(), 10))
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ping := time.NewTicker(watchPing)
+	defer ping.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-f.done:
+			return
+		case <-ping.C:
+			//empty line is skipped by readers of newline-delimited JSON
+			if sse {
+				_, err = fmt.Fprint(w, ": ping\n\n")
+			} else {
+				_, err = fmt.Fprint(w, "\n")
+			}
+		case e, ok := <-feed.Events():
+			if !ok {
+				if feed.Err() != nil {
+					writeFeedError(w, sse, feed.Err())
+					flusher.Flush()
+				}
+				return
+			}
+
+			err = writeChange(w, sse, e)
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+func writeChange(w http.ResponseWriter, sse bool, e core.Event) error {
+	data, err := json.Marshal(transaction.ToJSON(e))
+	if err != nil {
+		return err
+	}
+
+	if sse {
+		_, err = fmt.Fprintf(w, "id: %d\nevent: change\ndata: %s\n\n", e.ID, data)
+	} else {
+		_, err = fmt.Fprintf(w, "%s\n", data)
+	}
+
+	return err
+}
+
+func writeFeedError(w http.ResponseWriter, sse bool, err error) {
+	if sse {
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", err)
+		return
+	}
+
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	fmt.Fprintf(w, "%s\n", data)
+}
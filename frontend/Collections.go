@@ -0,0 +1,238 @@
+package frontend
+
+import (
+	"cache/core"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+func (f *Rest) routeCollections(router *mux.Router) {
+	router.HandleFunc("/v1/list/{key}", f.ListRange).Methods(http.MethodGet)
+	router.HandleFunc("/v1/list/{key}/{end:left|right}", f.ListPush).Methods(http.MethodPost)
+	router.HandleFunc("/v1/list/{key}/{end:left|right}", f.ListPop).Methods(http.MethodDelete)
+
+	router.HandleFunc("/v1/hash/{key}", f.HashGetAll).Methods(http.MethodGet)
+	router.HandleFunc("/v1/hash/{key}/{field}", f.HashGet).Methods(http.MethodGet)
+	router.HandleFunc("/v1/hash/{key}/{field}", f.HashSet).Methods(http.MethodPut)
+	router.HandleFunc("/v1/hash/{key}/{field}", f.HashDelete).Methods(http.MethodDelete)
+
+	router.HandleFunc("/v1/set/{key}", f.SetMembers).Methods(http.MethodGet)
+	router.HandleFunc("/v1/set/{key}/{member}", f.SetIsMember).Methods(http.MethodGet)
+	router.HandleFunc("/v1/set/{key}/{member}", f.SetAdd).Methods(http.MethodPut)
+	router.HandleFunc("/v1/set/{key}/{member}", f.SetRemove).Methods(http.MethodDelete)
+
+	router.HandleFunc("/v1/zset/{key}", f.ZRange).Methods(http.MethodGet)
+	router.HandleFunc("/v1/zset/{key}/popmin", f.ZPopMin).Methods(http.MethodPost)
+	router.HandleFunc("/v1/zset/{key}/{member}", f.ZMember).Methods(http.MethodGet)
+	router.HandleFunc("/v1/zset/{key}/{member}", f.ZAdd).Methods(http.MethodPut)
+	router.HandleFunc("/v1/zset/{key}/{member}", f.ZRemove).Methods(http.MethodDelete)
+}
+
+// writeStoreError maps errors of typed operations to status codes
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, core.ErrorNoSuchKey), errors.Is(err, core.ErrorNoSuchField), errors.Is(err, core.ErrorNoSuchMember):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, core.ErrorInvalidScore):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, core.ErrorWrongType):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, core.ErrorTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, core.ErrorNotLogged), errors.Is(err, core.ErrorReadOnly), errors.Is(err, core.ErrorFollower):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		fmt.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Println(err)
+	}
+}
+
+func writeText(w http.ResponseWriter, text string) {
+	if _, err := w.Write([]byte(text)); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func listEnd(r *http.Request) core.ListEnd {
+	if mux.Vars(r)["end"] == "left" {
+		return core.ListLeft
+	}
+
+	return core.ListRight
+}
+
+func (f *Rest) ListPush(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+
+	item, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+
+	length, err := f.storeFor(r).ListPush(key, listEnd(r), string(item))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeText(w, strconv.Itoa(length))
+}
+
+func (f *Rest) ListPop(w http.ResponseWriter, r *http.Request) {
+	item, err := f.storeFor(r).ListPop(mux.Vars(r)["key"], listEnd(r))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeText(w, item)
+}
+
+// ListRange returns items from "start" to "stop" query parameters inclusive,
+// by default whole list is returned
+func (f *Rest) ListRange(w http.ResponseWriter, r *http.Request) {
+	start, stop := 0, -1
+
+	for name, bound := range map[string]*int{"start": &start, "stop": &stop} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+
+		var err error
+		if *bound, err = strconv.Atoi(raw); err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s: %q", name, raw), http.StatusBadRequest)
+			return
+		}
+	}
+
+	items, err := f.store.ListRange(mux.Vars(r)["key"], start, stop)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, items)
+}
+
+func (f *Rest) HashGetAll(w http.ResponseWriter, r *http.Request) {
+	fields, err := f.store.HashGetAll(mux.Vars(r)["key"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, fields)
+}
+
+func (f *Rest) HashGet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	value, err := f.store.HashGet(vars["key"], vars["field"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeText(w, value)
+}
+
+func (f *Rest) HashSet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	value, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+
+	if err := f.storeFor(r).HashSet(vars["key"], vars["field"], string(value)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *Rest) HashDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	err := f.storeFor(r).HashDelete(vars["key"], vars["field"])
+	if err != nil && !errors.Is(err, core.ErrorNoSuchKey) && !errors.Is(err, core.ErrorNoSuchField) {
+		writeStoreError(w, err)
+	}
+}
+
+// SetMembers returns all members as JSON list or one random member
+// as text if "random" query parameter is present
+func (f *Rest) SetMembers(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+
+	if r.URL.Query().Has("random") {
+		member, err := f.store.SetRandomMember(key)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		writeText(w, member)
+		return
+	}
+
+	members, err := f.store.SetMembers(key)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, members)
+}
+
+func (f *Rest) SetIsMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	exists, err := f.store.SetIsMember(vars["key"], vars["member"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if !exists {
+		http.Error(w, "no such member", http.StatusNotFound)
+	}
+}
+
+func (f *Rest) SetAdd(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	added, err := f.storeFor(r).SetAdd(vars["key"], vars["member"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if added {
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (f *Rest) SetRemove(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	_, err := f.storeFor(r).SetRemove(vars["key"], vars["member"])
+	if err != nil && !errors.Is(err, core.ErrorNoSuchKey) {
+		writeStoreError(w, err)
+	}
+}
@@ -0,0 +1,52 @@
+package frontend
+
+import (
+	"cache/core"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+func (f *Rest) Incr(w http.ResponseWriter, r *http.Request) {
+	f.increment(w, r, 1)
+}
+
+func (f *Rest) Decr(w http.ResponseWriter, r *http.Request) {
+	f.increment(w, r, -1)
+}
+
+// increment adds "by" query parameter (1 by default) multiplied by sign to the key
+func (f *Rest) increment(w http.ResponseWriter, r *http.Request, sign int64) {
+	key := mux.Vars(r)["key"]
+
+	delta := int64(1)
+
+	if raw := r.URL.Query().Get("by"); raw != "" {
+		var err error
+		if delta, err = strconv.ParseInt(raw, 10, 64); err != nil || delta == math.MinInt64 {
+			http.Error(w, fmt.Sprintf("invalid by: %q", raw), http.StatusBadRequest)
+			return
+		}
+	}
+
+	value, err := f.storeFor(r).Increment(key, sign*delta)
+	if errors.Is(err, core.ErrorNotInteger) || errors.Is(err, core.ErrorOverflow) || errors.Is(err, core.ErrorWrongType) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if isUnavailable(w, err) {
+		return
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+
+	writeText(w, strconv.FormatInt(value, 10))
+}
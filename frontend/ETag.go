@@ -0,0 +1,92 @@
+package frontend
+
+import (
+	"cache/core"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errInvalidETag = errors.New("invalid etag")
+
+func etag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+func parseETag(raw string) (uint64, error) {
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "W/")
+
+	unquoted, err := strconv.Unquote(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", errInvalidETag, raw)
+	}
+
+	version, err := strconv.ParseUint(unquoted, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", errInvalidETag, raw)
+	}
+
+	return version, nil
+}
+
+func ifNotVersion(v uint64) core.Condition {
+	return func(version uint64, exists bool) error {
+		if exists && version == v {
+			return core.ErrorVersionMismatch
+		}
+
+		return nil
+	}
+}
+
+// condition builds store condition from If-Match and If-None-Match headers,
+// it returns nil condition if request is unconditional
+func condition(r *http.Request) (core.Condition, error) {
+	ifMatch := r.Header.Get("If-Match")
+	ifNoneMatch := r.Header.Get("If-None-Match")
+
+	switch {
+	case ifMatch != "" && ifNoneMatch != "":
+		return nil, errors.New("If-Match and If-None-Match could not be used together")
+	case ifMatch == "*":
+		return core.IfExists(), nil
+	case ifMatch != "":
+		version, err := parseETag(ifMatch)
+		if err != nil {
+			return nil, err
+		}
+
+		return core.IfVersion(version), nil
+	case ifNoneMatch == "*":
+		return core.IfAbsent(), nil
+	case ifNoneMatch != "":
+		version, err := parseETag(ifNoneMatch)
+		if err != nil {
+			return nil, err
+		}
+
+		return ifNotVersion(version), nil
+	default:
+		return nil, nil
+	}
+}
+
+// notModified reports whether If-None-Match header of read request matches current version
+func notModified(r *http.Request, version uint64) bool {
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch == "*" {
+		return true
+	}
+
+	expected, err := parseETag(ifNoneMatch)
+
+	return err == nil && expected == version
+}
+
+func isPreconditionFailed(err error) bool {
+	return errors.Is(err, core.ErrorVersionMismatch) ||
+		errors.Is(err, core.ErrorKeyExists) ||
+		errors.Is(err, core.ErrorNoSuchKey)
+}
@@ -0,0 +1,227 @@
+package frontend
+
+import (
+	"bufio"
+	"bytes"
+	"cache/core"
+	"cache/transaction"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// lastIDHeader tells ID of the last change of the leader when feed is opened
+const lastIDHeader = "X-Last-ID"
+
+// followRetry is the pause before follower connects to the leader again
+const followRetry = time.Second
+
+// Follower applies the change feed of the leader to its store, it starts from the
+// last change of its own log and reconnects when the feed ends. If the leader
+// compacted changes after it, follower bootstraps from the snapshot of the leader
+type Follower struct {
+	store  *core.Store
+	leader string
+	//proxy forwards changes of clients to the leader, nil if they are rejected
+	proxy *httputil.ReverseProxy
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu        sync.Mutex
+	connected bool
+	leaderID  uint64
+	//appliedAt is time of the last applied change on the leader
+	appliedAt time.Time
+	lostAt    time.Time
+	err       error
+}
+
+// Replication is the state of the follower, lag is zero when it has every
+// change of the leader, while it is disconnected lag counts from that moment
+type Replication struct {
+	Role       string  `json:"role"`
+	Leader     string  `json:"leader,omitempty"`
+	Connected  bool    `json:"connected"`
+	AppliedID  uint64  `json:"applied_id"`
+	LeaderID   uint64  `json:"leader_id,omitempty"`
+	LagEvents  uint64  `json:"lag_events"`
+	LagSeconds float64 `json:"lag_seconds"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// NewFollower makes the store a follower of the leader at the URL,
+// changes of clients are forwarded to the leader if forward is true
+func NewFollower(store *core.Store, leader string, forward bool) (*Follower, error) {
+	target, err := url.Parse(leader)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid URL of the leader: %q", leader)
+	}
+
+	store.Follow()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fl := &Follower{store: store, leader: leader, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+
+	if forward {
+		fl.proxy = httputil.NewSingleHostReverseProxy(target)
+	}
+
+	return fl, nil
+}
+
+// Start follows the leader in background until Shutdown
+func (fl *Follower) Start() {
+	go func() {
+		defer close(fl.done)
+
+		for {
+			err := fl.follow()
+
+			fl.mu.Lock()
+			if fl.connected {
+				fl.lostAt = time.Now()
+			}
+			fl.connected, fl.err = false, err
+			fl.mu.Unlock()
+
+			if fl.ctx.Err() != nil {
+				return
+			}
+
+			fmt.Println("replication from the leader is stopped:", err)
+
+			select {
+			case <-fl.ctx.Done():
+				return
+			case <-time.After(followRetry):
+			}
+		}
+	}()
+}
+
+func (fl *Follower) Shutdown(ctx context.Context) error {
+	fl.cancel()
+
+	select {
+	case <-fl.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// follow applies the feed until it ends
+func (fl *Follower) follow() error {
+	resp, err := fl.open(fl.store.LastID())
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusGone {
+		resp.Body.Close()
+		fmt.Println("changes after the last one of the follower were compacted by the leader, it starts from the snapshot")
+
+		if resp, err = fl.open(0); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("leader replied %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	leaderID, _ := strconv.ParseUint(resp.Header.Get(lastIDHeader), 10, 64)
+
+	fl.mu.Lock()
+	fl.connected, fl.err = true, nil
+	fl.leaderID = max(fl.leaderID, leaderID)
+	fl.mu.Unlock()
+
+	fmt.Println("follower is connected to the leader", fl.leader)
+
+	lines := bufio.NewReader(resp.Body)
+
+	for {
+		line, err := lines.ReadBytes('\n')
+		if err != nil {
+			return fmt.Errorf("read changes of the leader was failed: %w", err)
+		}
+
+		//empty line keeps idle connection open
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var change struct {
+			transaction.JSONEvent
+			Error string `json:"error"`
+		}
+		if err = json.Unmarshal(line, &change); err != nil {
+			return fmt.Errorf("decode change was failed: %w", err)
+		}
+		if change.Error != "" {
+			return errors.New(change.Error)
+		}
+
+		e, err := transaction.FromJSON(change.JSONEvent)
+		if err != nil {
+			return err
+		}
+
+		if err = fl.store.Replicate(e); err != nil {
+			return fmt.Errorf("apply change %d was failed: %w", e.ID, err)
+		}
+
+		fl.mu.Lock()
+		fl.leaderID = max(fl.leaderID, e.ID)
+		fl.appliedAt = time.Unix(0, e.Time)
+		fl.mu.Unlock()
+	}
+}
+
+func (fl *Follower) open(since uint64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(fl.ctx, http.MethodGet, fmt.Sprintf("%s/v1/changes?since=%d", fl.leader, since), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.DefaultClient.Do(req)
+}
+
+func (fl *Follower) Status() Replication {
+	applied := fl.store.LastID()
+
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	r := Replication{Role: "follower", Leader: fl.leader, Connected: fl.connected, AppliedID: applied, LeaderID: fl.leaderID}
+
+	//time of the last change is not known until follower applies one
+	if fl.leaderID > applied {
+		r.LagEvents = fl.leaderID - applied
+		if !fl.appliedAt.IsZero() {
+			r.LagSeconds = time.Since(fl.appliedAt).Seconds()
+		}
+	}
+	if !fl.connected && !fl.lostAt.IsZero() {
+		r.LagSeconds = max(r.LagSeconds, time.Since(fl.lostAt).Seconds())
+	}
+	if fl.err != nil {
+		r.Error = fl.err.Error()
+	}
+
+	return r
+}
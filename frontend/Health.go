@@ -0,0 +1,85 @@
+package frontend
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type healthResult struct {
+	Status string     `json:"status"`
+	Error  string     `json:"error,omitempty"`
+	Since  *time.Time `json:"since,omitempty"`
+}
+
+type leaderResult struct {
+	Role   string `json:"role"`
+	LastID uint64 `json:"last_id"`
+}
+
+// Health replies 503 while the store is read-only, so load balancers
+// could send changes to another instance
+func (f *Rest) Health(w http.ResponseWriter, _ *http.Request) {
+	health := f.store.Health()
+
+	if !health.ReadOnly {
+		writeJSON(w, healthResult{Status: "ok"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	writeJSON(w, healthResult{Status: "read-only", Error: health.Error.Error(), Since: &health.Since})
+}
+
+// Replication replies state of the follower, leader replies only its last change
+func (f *Rest) Replication(w http.ResponseWriter, _ *http.Request) {
+	if f.follower == nil {
+		writeJSON(w, leaderResult{Role: "leader", LastID: f.store.LastID()})
+		return
+	}
+
+	writeJSON(w, f.follower.Status())
+}
+
+// Metrics writes health of the store in Prometheus text format
+func (f *Rest) Metrics(w http.ResponseWriter, _ *http.Request) {
+	health := f.store.Health()
+
+	readOnly := 0
+	if health.ReadOnly {
+		readOnly = 1
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	writeText(w, fmt.Sprintf(`# HELP cache_read_only Whether the store rejects changes, because they could not be logged.
+# TYPE cache_read_only gauge
+cache_read_only %d
+# HELP cache_degradations_total Times the store became read-only.
+# TYPE cache_degradations_total counter
+cache_degradations_total %d
+`, readOnly, health.Degradations))
+
+	if f.follower == nil {
+		return
+	}
+
+	status := f.follower.Status()
+
+	connected := 0
+	if status.Connected {
+		connected = 1
+	}
+
+	writeText(w, fmt.Sprintf(`# HELP cache_replication_connected Whether the follower receives changes of the leader.
+# TYPE cache_replication_connected gauge
+cache_replication_connected %d
+# HELP cache_replication_lag_events Changes of the leader those are not applied by the follower.
+# TYPE cache_replication_lag_events gauge
+cache_replication_lag_events %d
+# HELP cache_replication_lag_seconds How far the state of the follower is behind the leader.
+# TYPE cache_replication_lag_seconds gauge
+cache_replication_lag_seconds %g
+`, connected, status.LagEvents, status.LagSeconds))
+}
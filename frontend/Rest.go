@@ -7,46 +7,110 @@ import (
 	"github.com/gorilla/mux"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+const ttlHeader = "X-TTL"
+
+// asyncHeader set to true makes the change reply without waiting until it is logged
+const asyncHeader = "X-Async"
+
 type Rest struct {
 	store *core.Store
+	//closed when server is shutting down, so streams do not hold it
+	done chan struct{}
+	//zero means that size of request body is not limited
+	maxBodySize int64
+	//follower is nil if the store is the leader
+	follower *Follower
+
+	//staged is the store restored to the point of history, nil if there is none
+	stagedMu sync.Mutex
+	staged   *core.Store
 }
 
-func NewRest(store *core.Store, port string) *http.Server {
+// NewRest creates server, bodies of requests bigger than maxBodySize are rejected,
+// follower is nil if the store is the leader
+func NewRest(store *core.Store, port string, maxBodySize int64, follower *Follower) *http.Server {
 	router := mux.NewRouter()
-	f := &Rest{store}
+	f := &Rest{store: store, done: make(chan struct{}), maxBodySize: maxBodySize, follower: follower}
+
+	router.Use(f.limitBody, f.forward)
 
+	router.HandleFunc("/v1", f.Scan).Methods(http.MethodGet)
+	//before get of the key, so key "changes" is not read by GET
+	router.HandleFunc("/v1/changes", f.Changes).Methods(http.MethodGet)
 	router.HandleFunc("/v1/{key}", f.Put).Methods(http.MethodPut)
 	router.HandleFunc("/v1/{key}", f.Get).Methods(http.MethodGet)
 	router.HandleFunc("/v1/{key}", f.Delete).Methods(http.MethodDelete)
 	router.HandleFunc("/v1/operation/clear", f.Clear).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/operation/txn", f.Txn).Methods(http.MethodPost)
+	router.HandleFunc("/v1/operation/snapshot", f.Snapshot).Methods(http.MethodPost)
+	router.HandleFunc("/v1/operation/restore", f.Restore).Methods(http.MethodPost)
+	router.HandleFunc("/v1/operation/restore", f.RestoredScan).Methods(http.MethodGet)
+	router.HandleFunc("/v1/operation/restore", f.DiscardRestored).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/operation/restore/promote", f.Promote).Methods(http.MethodPost)
+	router.HandleFunc("/v1/operation/restore/{key}", f.RestoredGet).Methods(http.MethodGet)
+	router.HandleFunc("/v1/{key}/incr", f.Incr).Methods(http.MethodPost)
+	router.HandleFunc("/v1/{key}/decr", f.Decr).Methods(http.MethodPost)
+	router.HandleFunc("/v1/watch/{key}", f.Watch).Methods(http.MethodGet)
+	router.HandleFunc("/v1/operation/health", f.Health).Methods(http.MethodGet)
+	router.HandleFunc("/v1/operation/replication", f.Replication).Methods(http.MethodGet)
+	router.HandleFunc("/metrics", f.Metrics).Methods(http.MethodGet)
+	f.routeCollections(router)
 
 	s := http.Server{
 		Addr:    ":" + port,
 		Handler: router,
 	}
 
+	s.RegisterOnShutdown(func() {
+		close(f.done)
+	})
+
 	return &s
 }
 
 func (f *Rest) Get(w http.ResponseWriter, r *http.Request) {
 	key := mux.Vars(r)["key"]
 
-	value, err := f.store.Get(key)
+	item, err := f.store.GetItem(key)
 	if errors.Is(err, core.ErrorNoSuchKey) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		fmt.Println(err)
 		return
 	}
 
+	if errors.Is(err, core.ErrorWrongType) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Println(err)
 		return
 	}
 
-	if _, err = w.Write([]byte(value)); err != nil {
+	w.Header().Set("ETag", etag(item.Version))
+
+	if item.TTL != 0 {
+		w.Header().Set(ttlHeader, item.TTL.Round(time.Millisecond).String())
+	}
+
+	if notModified(r, item.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if item.ContentType != "" {
+		w.Header().Set("Content-Type", item.ContentType)
+	}
+
+	if _, err = w.Write(item.Value); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		fmt.Println(err)
 		return
@@ -56,24 +120,194 @@ func (f *Rest) Get(w http.ResponseWriter, r *http.Request) {
 func (f *Rest) Put(w http.ResponseWriter, r *http.Request) {
 	key := mux.Vars(r)["key"]
 
-	value, err := io.ReadAll(r.Body)
-	defer r.Body.Close()
+	value, ok := readBody(w, r)
+	if !ok {
+		return
+	}
 
+	ttl, err := parseTTL(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		fmt.Println(err)
 		return
 	}
 
-	f.store.Put(key, string(value))
+	cond, err := condition(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	item := core.Item{Value: value, ContentType: r.Header.Get("Content-Type"), TTL: ttl}
+
+	version, err := f.storeFor(r).PutItem(key, item, cond)
+	if isPreconditionFailed(err) {
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	if isUnavailable(w, err) {
+		return
+	}
+
+	if errors.Is(err, core.ErrorTooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(version))
 	w.WriteHeader(http.StatusCreated)
 }
 
-func (f *Rest) Delete(_ http.ResponseWriter, r *http.Request) {
+// storeFor returns store view for changes of the request, by default they wait
+// until they are logged, so failed write is reported to the client
+func (f *Rest) storeFor(r *http.Request) *core.Store {
+	if async, err := strconv.ParseBool(r.Header.Get(asyncHeader)); err == nil && async {
+		return f.store.Async()
+	}
+
+	return f.store
+}
+
+// isUnavailable writes response if change was applied, but was not logged,
+// so it could be lost on restart, or it was rejected by read-only store or follower
+func isUnavailable(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, core.ErrorNotLogged) && !errors.Is(err, core.ErrorReadOnly) && !errors.Is(err, core.ErrorFollower) {
+		return false
+	}
+
+	http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	fmt.Println(err)
+	return true
+}
+
+func (f *Rest) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.maxBodySize > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, f.maxBodySize)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forward sends changes to the leader if the store is its follower, so clients
+// could use any instance. Point-in-time restore is staged on the leader too,
+// snapshot is taken of the log of the follower
+func (f *Rest) forward(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.follower == nil || f.follower.proxy == nil || r.URL.Path == "/v1/operation/snapshot" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		read := r.Method == http.MethodGet || r.Method == http.MethodHead
+		if read && !strings.HasPrefix(r.URL.Path, "/v1/operation/restore") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		f.follower.proxy.ServeHTTP(w, r)
+	})
+}
+
+// readBody reads whole body of the request, if it fails response
+// is already written and false is returned
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+
+	if isTooLarge(w, err) {
+		return nil, false
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Println(err)
+		return nil, false
+	}
+
+	return body, true
+}
+
+// isTooLarge writes response if body of the request is bigger than the limit
+func isTooLarge(w http.ResponseWriter, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+
+	http.Error(w, fmt.Sprintf("body is bigger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+	return true
+}
+
+// parseTTL reads ttl from "ttl" query parameter or X-TTL header in
+// time.ParseDuration format, zero means that ttl was not specified
+func parseTTL(r *http.Request) (time.Duration, error) {
+	raw := r.URL.Query().Get("ttl")
+	if raw == "" {
+		raw = r.Header.Get(ttlHeader)
+	}
+
+	if raw == "" {
+		return 0, nil
+	}
+
+	ttl, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ttl: %w", err)
+	}
+
+	if ttl <= 0 {
+		return 0, core.ErrorInvalidTTL
+	}
+
+	return ttl, nil
+}
+
+func (f *Rest) Delete(w http.ResponseWriter, r *http.Request) {
 	key := mux.Vars(r)["key"]
-	f.store.Delete(key)
+
+	cond, err := condition(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	if cond == nil {
+		isUnavailable(w, f.storeFor(r).Delete(key))
+		return
+	}
+
+	if err = f.storeFor(r).DeleteIf(key, cond); isPreconditionFailed(err) {
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	isUnavailable(w, err)
 }
 
-func (f *Rest) Clear(_ http.ResponseWriter, r *http.Request) {
-	f.store.Clear()
+func (f *Rest) Clear(w http.ResponseWriter, r *http.Request) {
+	isUnavailable(w, f.storeFor(r).Clear())
+}
+
+func (f *Rest) Snapshot(w http.ResponseWriter, _ *http.Request) {
+	err := f.store.Snapshot()
+	if errors.Is(err, core.ErrorSnapshotNotSupported) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		fmt.Println(err)
+	}
 }
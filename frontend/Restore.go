@@ -0,0 +1,96 @@
+package frontend
+
+import (
+	"cache/core"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var errNothingRestored = errors.New("nothing is restored, restore it first")
+
+// Restore rebuilds separate store as it was at the point given by "to" query
+// parameter, that is event ID or RFC 3339 time. It replaces the previous restored
+// store and could be inspected read only until it is promoted or discarded
+func (f *Rest) Restore(w http.ResponseWriter, r *http.Request) {
+	point, err := core.ParsePoint(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	staged, err := f.store.At(point)
+	if errors.Is(err, core.ErrorHistoryCompacted) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+
+	f.stagedMu.Lock()
+	f.staged = staged
+	f.stagedMu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// inspect serves read only request by the restored store
+func (f *Rest) inspect(w http.ResponseWriter, r *http.Request, handler func(*Rest, http.ResponseWriter, *http.Request)) {
+	f.stagedMu.Lock()
+	staged := f.staged
+	f.stagedMu.Unlock()
+
+	if staged == nil {
+		http.Error(w, errNothingRestored.Error(), http.StatusNotFound)
+		return
+	}
+
+	handler(&Rest{store: staged}, w, r)
+}
+
+func (f *Rest) RestoredGet(w http.ResponseWriter, r *http.Request) {
+	f.inspect(w, r, (*Rest).Get)
+}
+
+func (f *Rest) RestoredScan(w http.ResponseWriter, r *http.Request) {
+	f.inspect(w, r, (*Rest).Scan)
+}
+
+func (f *Rest) DiscardRestored(w http.ResponseWriter, _ *http.Request) {
+	f.stagedMu.Lock()
+	defer f.stagedMu.Unlock()
+
+	if f.staged == nil {
+		http.Error(w, errNothingRestored.Error(), http.StatusNotFound)
+		return
+	}
+
+	f.staged = nil
+}
+
+// Promote replaces the whole store by the restored one, ETag of response
+// is version those all keys get
+func (f *Rest) Promote(w http.ResponseWriter, r *http.Request) {
+	f.stagedMu.Lock()
+	defer f.stagedMu.Unlock()
+
+	if f.staged == nil {
+		http.Error(w, errNothingRestored.Error(), http.StatusNotFound)
+		return
+	}
+
+	version, err := f.storeFor(r).Promote(f.staged)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	//failed promote could be retried with the same staged store
+	f.staged = nil
+
+	w.Header().Set("ETag", etag(version))
+}
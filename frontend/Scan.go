@@ -0,0 +1,83 @@
+package frontend
+
+import (
+	"cache/core"
+	"cache/transaction"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultScanLimit = 100
+	maxScanLimit     = 1000
+)
+
+type scanResult struct {
+	Keys []string `json:"keys"`
+	//values of string keys, only if "values" query parameter is present,
+	//values those are not UTF-8 are in ValuesBase64
+	Values       map[string]string `json:"values,omitempty"`
+	ValuesBase64 map[string]string `json:"values_base64,omitempty"`
+	//empty when scan is finished
+	Next string `json:"next"`
+}
+
+// Scan returns page of keys those start with "prefix" query parameter,
+// next page is requested with "cursor" from the previous response
+func (f *Rest) Scan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := defaultScanLimit
+
+	if raw := query.Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 || limit > maxScanLimit {
+			http.Error(w, fmt.Sprintf("invalid limit: %q, it should be from 1 to %d", raw, maxScanLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	keys, next, err := f.store.Scan(query.Get("prefix"), query.Get("cursor"), limit)
+	if errors.Is(err, core.ErrorInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+
+	result := scanResult{Keys: keys, Next: next}
+
+	if query.Has("values") {
+		result.Values = make(map[string]string, len(keys))
+
+		//keys could be changed since scan, missing keys and collections are skipped
+		for _, key := range keys {
+			value, err := f.store.Get(key)
+			if err != nil {
+				continue
+			}
+
+			text, encoded := transaction.EncodeValue(value)
+
+			switch {
+			case text != nil:
+				result.Values[key] = *text
+			case encoded != "":
+				if result.ValuesBase64 == nil {
+					result.ValuesBase64 = make(map[string]string)
+				}
+
+				result.ValuesBase64[key] = encoded
+			default:
+				result.Values[key] = ""
+			}
+		}
+	}
+
+	writeJSON(w, result)
+}
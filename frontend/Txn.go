@@ -0,0 +1,87 @@
+package frontend
+
+import (
+	"cache/core"
+	"cache/transaction"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// txnOperation has value as text or as base64 in ValueBase64, like watch events
+type txnOperation struct {
+	Op          string  `json:"op"`
+	Key         string  `json:"key"`
+	Value       *string `json:"value"`
+	ValueBase64 string  `json:"value_base64,omitempty"`
+	TTL         string  `json:"ttl,omitempty"`
+}
+
+type txnResult struct {
+	Version uint64 `json:"version"`
+}
+
+func (f *Rest) Txn(w http.ResponseWriter, r *http.Request) {
+	var ops []txnOperation
+
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(&ops); isTooLarge(w, err) {
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("invalid transaction: %s", err), http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	txn := f.storeFor(r).Begin()
+
+	for i, op := range ops {
+		switch op.Op {
+		case "put":
+			value, err := transaction.DecodeValue(op.Value, op.ValueBase64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("operation %d: %s", i, err), http.StatusBadRequest)
+				return
+			}
+
+			if op.TTL == "" {
+				txn.Put(op.Key, value)
+				continue
+			}
+
+			ttl, err := time.ParseDuration(op.TTL)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("operation %d: invalid ttl: %s", i, err), http.StatusBadRequest)
+				return
+			}
+
+			txn.PutWithTTL(op.Key, value, ttl)
+		case "delete":
+			txn.Delete(op.Key)
+		default:
+			http.Error(w, fmt.Sprintf("operation %d: unknown op %q", i, op.Op), http.StatusBadRequest)
+			return
+		}
+	}
+
+	version, err := txn.Commit()
+	if isUnavailable(w, err) {
+		return
+	}
+
+	if errors.Is(err, core.ErrorTooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	writeJSON(w, txnResult{version})
+}
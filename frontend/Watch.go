@@ -0,0 +1,97 @@
+package frontend
+
+import (
+	"cache/core"
+	"cache/transaction"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+const (
+	//number of events those could wait for slow client before it is disconnected
+	watchBuffer = 256
+	//comment is sent periodically, so proxies do not close idle connection
+	watchPing = 30 * time.Second
+)
+
+// watchEvent has value as text if it is UTF-8, otherwise it is in ValueBase64
+type watchEvent struct {
+	Key         string  `json:"key"`
+	Field       string  `json:"field,omitempty"`
+	Value       *string `json:"value,omitempty"`
+	ValueBase64 string  `json:"value_base64,omitempty"`
+}
+
+// Watch streams changes of the key as Server-Sent Events, the key is treated
+// as prefix if "prefix" query parameter is present. Stream ends with "error"
+// event if client does not keep up with changes
+func (f *Rest) Watch(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	watcher := f.store.Watch(mux.Vars(r)["key"], r.URL.Query().Has("prefix"), watchBuffer)
+	defer watcher.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ping := time.NewTicker(watchPing)
+	defer ping.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-f.done:
+			return
+		case <-ping.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		case e, ok := <-watcher.Events():
+			if !ok {
+				if watcher.Err() != nil {
+					fmt.Fprintf(w, "event: error\ndata: %s\n\n", watcher.Err())
+					flusher.Flush()
+				}
+				return
+			}
+
+			err = writeEvent(w, e)
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e core.Event) error {
+	event := watchEvent{Key: e.Key, Field: e.Field}
+	event.Value, event.ValueBase64 = transaction.EncodeValue(e.Value)
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	//watcher sees new value of the key, not how long it lives
+	name := core.EventName(e.Type)
+	if e.Type == core.EventPutWithTTL {
+		name = core.EventName(core.EventPut)
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, name, data)
+	return err
+}
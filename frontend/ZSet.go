@@ -0,0 +1,137 @@
+package frontend
+
+import (
+	"cache/core"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+type zsetMember struct {
+	core.ZMember
+	Rank int `json:"rank"`
+}
+
+// ZRange returns members ordered by score, members with score between "min"
+// and "max" query parameters are returned if any of them is given, otherwise
+// members from "start" to "stop" rank inclusive, by default whole set is returned
+func (f *Rest) ZRange(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	query := r.URL.Query()
+
+	if query.Has("min") || query.Has("max") {
+		low, high := math.Inf(-1), math.Inf(1)
+
+		for name, bound := range map[string]*float64{"min": &low, "max": &high} {
+			raw := query.Get(name)
+			if raw == "" {
+				continue
+			}
+
+			var err error
+			if *bound, err = strconv.ParseFloat(raw, 64); err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %q", name, raw), http.StatusBadRequest)
+				return
+			}
+		}
+
+		members, err := f.store.ZRangeByScore(key, low, high)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		writeJSON(w, members)
+		return
+	}
+
+	start, stop := 0, -1
+
+	for name, bound := range map[string]*int{"start": &start, "stop": &stop} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+
+		var err error
+		if *bound, err = strconv.Atoi(raw); err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s: %q", name, raw), http.StatusBadRequest)
+			return
+		}
+	}
+
+	members, err := f.store.ZRange(key, start, stop)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, members)
+}
+
+// ZMember returns score and rank of the member
+func (f *Rest) ZMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	score, err := f.store.ZScore(vars["key"], vars["member"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	rank, err := f.store.ZRank(vars["key"], vars["member"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, zsetMember{core.ZMember{Member: vars["member"], Score: score}, rank})
+}
+
+// ZAdd sets score of the member to the body of request
+func (f *Rest) ZAdd(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+
+	score, err := strconv.ParseFloat(string(body), 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid score: %q", body), http.StatusBadRequest)
+		return
+	}
+
+	added, err := f.storeFor(r).ZAdd(vars["key"], vars["member"], score)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if added {
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (f *Rest) ZRemove(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	_, err := f.storeFor(r).ZRemove(vars["key"], vars["member"])
+	if err != nil && !errors.Is(err, core.ErrorNoSuchKey) {
+		writeStoreError(w, err)
+	}
+}
+
+func (f *Rest) ZPopMin(w http.ResponseWriter, r *http.Request) {
+	member, err := f.storeFor(r).ZPopMin(mux.Vars(r)["key"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, member)
+}
@@ -2,6 +2,4 @@ module cache
 
 go 1.23.0
 
-require (
-	github.com/gorilla/mux v1.8.1 // indirect
-)
+require github.com/gorilla/mux v1.8.1
@@ -3,8 +3,10 @@ package main
 import (
 	"cache/config"
 	"cache/core"
+	"cache/eviction"
 	"cache/frontend"
 	"cache/transaction"
+	"cache/transaction/binaryEvent"
 	"context"
 	"errors"
 	"fmt"
@@ -37,21 +39,140 @@ func HandelShutdown(timeout time.Duration, services ...shutdownAble) {
 func main() {
 	cfg := config.Get()
 
-	tl, err := transaction.NewLogger(cfg.LogsPath, cfg.Bandwidth)
+	keys, err := transaction.LoadKeyring(cfg.KeyFile)
 	if err != nil {
 		panic(err)
 	}
 
-	tl.Start()
+	if cfg.Migrate {
+		if err = transaction.Migrate(cfg.LogsPath, keys); err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
+	if cfg.Reencrypt {
+		if err = transaction.Reencrypt(cfg.LogsPath, keys); err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
+	policy, err := transaction.ParseCorruptionPolicy(cfg.Corruption)
+	if err != nil {
+		panic(err)
+	}
+
+	durability, err := transaction.ParseDurability(cfg.Durability)
+	if err != nil {
+		panic(err)
+	}
+
+	compression, err := binaryEvent.ParseCompression(cfg.Compression)
+	if err != nil {
+		panic(err)
+	}
+
+	format, err := transaction.ParseFormat(cfg.LogFormat)
+	if err != nil {
+		panic(err)
+	}
+
+	tl, err := transaction.NewLogger(cfg.LogsPath, transaction.Options{
+		Bandwidth:      cfg.Bandwidth,
+		Corruption:     policy,
+		SegmentSize:    cfg.SegmentSize,
+		SegmentAge:     cfg.SegmentAge,
+		RetainSegments: cfg.RetainSegments,
+		Durability:     durability,
+		SyncInterval:   cfg.SyncInterval,
+		Compression:    compression,
+		Keys:           keys,
+		Format:         format,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	logErrs := tl.Start()
+
+	store := core.NewShardedStore(tl, cfg.Shards)
+
+	//failed writes are reported to the clients those wait for them,
+	//store becomes read-only for everybody until the log recovers
+	go func() {
+		for err := range logErrs {
+			fmt.Println("Error writing log:", err)
+			store.Degrade(err)
+		}
+	}()
+
+	var follower *frontend.Follower
+
+	if cfg.Leader != "" {
+		if follower, err = frontend.NewFollower(store, cfg.Leader, cfg.ForwardWrites); err != nil {
+			panic(err)
+		}
+
+		if cfg.MaxKeys > 0 || cfg.MaxBytes > 0 || cfg.RestoreTo != "" {
+			fmt.Println("warning: limits and restore_to are ignored by follower, they are set on the leader")
+		}
+	}
+
+	if follower == nil && (cfg.MaxKeys > 0 || cfg.MaxBytes > 0) {
+		newPolicy, err := eviction.Factory(cfg.EvictionPolicy)
+		if err != nil {
+			panic(err)
+		}
+
+		if err = core.CheckLimits(cfg.MaxKeys, cfg.MaxBytes, cfg.Shards); err != nil {
+			panic(err)
+		}
+
+		store.WithEviction(newPolicy, cfg.MaxKeys, cfg.MaxBytes)
+	}
 
-	store := core.NewStore(tl)
 	if err = store.Restore(); err != nil {
 		panic(err)
 	}
 
-	server := frontend.NewRest(store, cfg.Port)
+	if follower == nil && cfg.RestoreTo != "" {
+		point, err := core.ParsePoint(cfg.RestoreTo)
+		if err != nil {
+			panic(err)
+		}
+
+		staged, err := store.At(point)
+		if err != nil {
+			panic(err)
+		}
+
+		version, err := store.Promote(staged)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Println("store is rolled back to", cfg.RestoreTo, "version", version)
+	}
+
+	store.StartHealthCheck(cfg.HealthInterval)
+	store.StartSnapshots(cfg.SnapshotEvery, cfg.SnapshotLogSize)
+
+	server := frontend.NewRest(store, cfg.Port, cfg.MaxBodySize, follower)
+
+	services := []shutdownAble{server, store, tl}
+
+	//follower removes expired and evicted keys by events of the leader
+	if follower == nil {
+		store.StartReaper(cfg.ReaperInterval)
+	} else {
+		follower.Start()
+		services = []shutdownAble{server, follower, store, tl}
+	}
 
-	go HandelShutdown(cfg.TimeForShutdown, server, tl)
+	go HandelShutdown(cfg.TimeForShutdown, services...)
 
 	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) && err != nil {
 		panic(err)
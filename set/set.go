@@ -49,7 +49,21 @@ func (s *Set) Random() string {
 	return s.indexes[randomIndex]
 }
 
+func (s *Set) Contains(item string) bool {
+	_, exists := s.elements[item]
+	return exists
+}
+
+// Members returns copy of all items in no particular order
+func (s *Set) Members() []string {
+	return append([]string(nil), s.indexes...)
+}
+
+func (s *Set) Len() int {
+	return len(s.indexes)
+}
+
 func (s *Set) Clear() {
 	clear(s.elements)
-	clear(s.indexes)
+	s.indexes = s.indexes[:0]
 }
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 )
@@ -40,13 +41,26 @@ func (a *TestingApp) WithPort(port string) *TestingApp {
 	return a
 }
 
+func (a *TestingApp) WithLogsPath(path string) *TestingApp {
+	a.args = append(a.args, fmt.Sprintf("-logs_path=%s", path))
+	return a
+}
+
+func (a *TestingApp) WithArg(name string, value string) *TestingApp {
+	a.args = append(a.args, fmt.Sprintf("-%s=%s", name, value))
+	return a
+}
+
 func (a *TestingApp) Start() {
 	//compile app file
 	if err := exec.Command("go", "build", a.location).Run(); err != nil {
 		log.Fatal(err)
 	}
 
-	executable := "./" + strings.TrimSuffix(filepath.Base(a.location), filepath.Ext(a.location)) + ".exe"
+	executable := "./" + strings.TrimSuffix(filepath.Base(a.location), filepath.Ext(a.location))
+	if runtime.GOOS == "windows" {
+		executable += ".exe"
+	}
 
 	a.cmd = exec.Command(executable, a.args...)
 
@@ -96,6 +110,16 @@ func (a *TestingApp) GetRequest(key string) (string, error) {
 	return string(value), err
 }
 
+func (a *TestingApp) GetTTLRequest(key string) (string, error) {
+	resp, err := http.Get(a.root + "/v1/" + key)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	return resp.Header.Get("X-TTL"), nil
+}
+
 func (a *TestingApp) CheckGetRequest(key string, want string) error {
 	value, err := a.GetRequest(key)
 	if err != nil {
@@ -110,6 +134,10 @@ func (a *TestingApp) CheckGetRequest(key string, want string) error {
 }
 
 func (a *TestingApp) PutRequest(key string, value string) error {
+	return a.PutRequestWithTTL(key, value, "")
+}
+
+func (a *TestingApp) PutRequestWithTTL(key string, value string, ttl string) error {
 	url := a.root + "/v1/" + key
 
 	req, err := http.NewRequest("PUT", url, strings.NewReader(value))
@@ -117,6 +145,10 @@ func (a *TestingApp) PutRequest(key string, value string) error {
 		return err
 	}
 
+	if ttl != "" {
+		req.Header.Set("X-TTL", ttl)
+	}
+
 	resp, err := (&http.Client{}).Do(req)
 	if err != nil {
 		return err
@@ -165,3 +197,47 @@ func (a *TestingApp) ClearRequest() error {
 
 	return nil
 }
+
+// Request sends request to the given path with optional body and headers,
+// it returns status code, response headers and body
+func (a *TestingApp) Request(method string, path string, body string, headers map[string]string) (int, http.Header, string, error) {
+	req, err := http.NewRequest(method, a.root+path, strings.NewReader(body))
+	if err != nil {
+		return 0, nil, "", err
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return 0, nil, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, "", err
+	}
+
+	return resp.StatusCode, resp.Header, string(respBody), nil
+}
+
+// Stream opens long-lived GET request, caller should close body of the response
+func (a *TestingApp) Stream(path string) (*http.Response, error) {
+	return http.Get(a.root + path)
+}
+
+// StreamWithHeader opens long-lived GET request with the header,
+// caller should close body of the response
+func (a *TestingApp) StreamWithHeader(path string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, a.root+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = header
+
+	return http.DefaultClient.Do(req)
+}
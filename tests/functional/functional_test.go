@@ -3,8 +3,16 @@ package functional
 //import only errors from core
 
 import (
+	"bufio"
 	"cache/tests"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 type request struct {
@@ -15,7 +23,9 @@ type request struct {
 //todo test restoring after term
 
 func TestBasicCases(t *testing.T) {
-	a := tests.NewApp("../../main.go").WithPort("9989")
+	a := tests.NewApp("../../main.go").
+		WithPort("9989").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin"))
 
 	a.Start()
 	defer a.Stop()
@@ -73,3 +83,764 @@ func TestBasicCases(t *testing.T) {
 		}
 	})
 }
+
+func TestTTL(t *testing.T) {
+	a := tests.NewApp("../../main.go").
+		WithPort("9990").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin")).
+		WithArg("reaper_interval", "100ms")
+
+	a.Start()
+	defer a.Stop()
+
+	req := request{"key with ttl", "value"}
+
+	t.Run("put with ttl", func(t *testing.T) {
+		if err := a.PutRequestWithTTL(req.key, req.value, "1s"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := a.CheckGetRequest(req.key, req.value); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("remaining ttl", func(t *testing.T) {
+		ttl, err := a.GetTTLRequest(req.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		remaining, err := time.ParseDuration(ttl)
+		if err != nil {
+			t.Fatalf("invalid ttl header %q: %v", ttl, err)
+		}
+		if remaining <= 0 || remaining > time.Second {
+			t.Fatalf("got remaining ttl %v, want in (0, 1s]", remaining)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		time.Sleep(1500 * time.Millisecond)
+
+		if err := a.CheckNoSuchKey(req.key); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("invalid ttl", func(t *testing.T) {
+		if err := a.PutRequestWithTTL(req.key, req.value, "-1s"); err == nil {
+			t.Fatal("put with negative ttl was accepted")
+		}
+	})
+}
+
+func TestConditionalRequests(t *testing.T) {
+	a := tests.NewApp("../../main.go").
+		WithPort("9991").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin"))
+
+	a.Start()
+	defer a.Stop()
+
+	var etag string
+
+	t.Run("put if none match", func(t *testing.T) {
+		code, header, _, err := a.Request(http.MethodPut, "/v1/key", "first", map[string]string{"If-None-Match": "*"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != http.StatusCreated {
+			t.Fatalf("got status %d, want %d", code, http.StatusCreated)
+		}
+
+		etag = header.Get("ETag")
+
+		code, _, _, err = a.Request(http.MethodPut, "/v1/key", "second", map[string]string{"If-None-Match": "*"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != http.StatusPreconditionFailed {
+			t.Fatalf("got status %d, want %d", code, http.StatusPreconditionFailed)
+		}
+	})
+
+	t.Run("get etag", func(t *testing.T) {
+		code, header, _, err := a.Request(http.MethodGet, "/v1/key", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != http.StatusOK || header.Get("ETag") != etag {
+			t.Fatalf("got status %d etag %q, want %d %q", code, header.Get("ETag"), http.StatusOK, etag)
+		}
+
+		code, _, _, err = a.Request(http.MethodGet, "/v1/key", "", map[string]string{"If-None-Match": etag})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != http.StatusNotModified {
+			t.Fatalf("got status %d, want %d", code, http.StatusNotModified)
+		}
+	})
+
+	t.Run("put if match", func(t *testing.T) {
+		code, header, _, err := a.Request(http.MethodPut, "/v1/key", "second", map[string]string{"If-Match": etag})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != http.StatusCreated {
+			t.Fatalf("got status %d, want %d", code, http.StatusCreated)
+		}
+
+		code, _, _, err = a.Request(http.MethodPut, "/v1/key", "third", map[string]string{"If-Match": etag})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != http.StatusPreconditionFailed {
+			t.Fatalf("stale etag: got status %d, want %d", code, http.StatusPreconditionFailed)
+		}
+
+		etag = header.Get("ETag")
+
+		if err = a.CheckGetRequest("key", "second"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("delete if match", func(t *testing.T) {
+		code, _, _, err := a.Request(http.MethodDelete, "/v1/key", "", map[string]string{"If-Match": `"0"`})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != http.StatusPreconditionFailed {
+			t.Fatalf("got status %d, want %d", code, http.StatusPreconditionFailed)
+		}
+
+		code, _, _, err = a.Request(http.MethodDelete, "/v1/key", "", map[string]string{"If-Match": etag})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", code, http.StatusOK)
+		}
+
+		if err = a.CheckNoSuchKey("key"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestTxn(t *testing.T) {
+	a := tests.NewApp("../../main.go").
+		WithPort("9992").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin"))
+
+	a.Start()
+	defer a.Stop()
+
+	if err := a.PutRequest("deleted", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		body := `[
+			{"op": "put", "key": "first", "value": "1"},
+			{"op": "put", "key": "second", "value": "2", "ttl": "1h"},
+			{"op": "delete", "key": "deleted"}
+		]`
+
+		code, _, _, err := a.Request(http.MethodPost, "/v1/operation/txn", body, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", code, http.StatusOK)
+		}
+
+		if err = a.CheckGetRequest("first", "1"); err != nil {
+			t.Fatal(err)
+		}
+		if err = a.CheckGetRequest("second", "2"); err != nil {
+			t.Fatal(err)
+		}
+		if err = a.CheckNoSuchKey("deleted"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("invalid operation", func(t *testing.T) {
+		body := `[
+			{"op": "put", "key": "third", "value": "3"},
+			{"op": "rename", "key": "first"}
+		]`
+
+		code, _, _, err := a.Request(http.MethodPost, "/v1/operation/txn", body, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != http.StatusBadRequest {
+			t.Fatalf("got status %d, want %d", code, http.StatusBadRequest)
+		}
+
+		if err = a.CheckNoSuchKey("third"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestCounters(t *testing.T) {
+	a := tests.NewApp("../../main.go").
+		WithPort("9993").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin"))
+
+	a.Start()
+	defer a.Stop()
+
+	steps := []struct {
+		path string
+		code int
+		want string
+	}{
+		{"/v1/counter/incr", http.StatusOK, "1"},
+		{"/v1/counter/incr?by=10", http.StatusOK, "11"},
+		{"/v1/counter/decr?by=4", http.StatusOK, "7"},
+		{"/v1/counter/decr", http.StatusOK, "6"},
+		{"/v1/counter/incr?by=abc", http.StatusBadRequest, ""},
+	}
+
+	for _, step := range steps {
+		code, _, body, err := a.Request(http.MethodPost, step.path, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != step.code {
+			t.Fatalf("%s: got status %d, want %d", step.path, code, step.code)
+		}
+		if code == http.StatusOK && body != step.want {
+			t.Fatalf("%s: got %q, want %q", step.path, body, step.want)
+		}
+	}
+
+	if err := a.PutRequest("text", "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, _, err := a.Request(http.MethodPost, "/v1/text/incr", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusConflict {
+		t.Fatalf("got status %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestTypedValues(t *testing.T) {
+	a := tests.NewApp("../../main.go").
+		WithPort("9994").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin"))
+
+	a.Start()
+	defer a.Stop()
+
+	steps := []struct {
+		method string
+		path   string
+		body   string
+		code   int
+		want   string
+	}{
+		{http.MethodPost, "/v1/list/list/right", "b", http.StatusOK, "1"},
+		{http.MethodPost, "/v1/list/list/left", "a", http.StatusOK, "2"},
+		{http.MethodGet, "/v1/list/list", "", http.StatusOK, `["a","b"]` + "\n"},
+		{http.MethodDelete, "/v1/list/list/right", "", http.StatusOK, "b"},
+
+		{http.MethodPut, "/v1/hash/hash/field", "value", http.StatusCreated, ""},
+		{http.MethodGet, "/v1/hash/hash/field", "", http.StatusOK, "value"},
+		{http.MethodGet, "/v1/hash/hash", "", http.StatusOK, `{"field":"value"}` + "\n"},
+		{http.MethodDelete, "/v1/hash/hash/field", "", http.StatusOK, ""},
+		{http.MethodGet, "/v1/hash/hash/field", "", http.StatusNotFound, ""},
+
+		{http.MethodPut, "/v1/set/set/member", "", http.StatusCreated, ""},
+		{http.MethodPut, "/v1/set/set/member", "", http.StatusOK, ""},
+		{http.MethodGet, "/v1/set/set/member", "", http.StatusOK, ""},
+		{http.MethodGet, "/v1/set/set?random", "", http.StatusOK, "member"},
+		{http.MethodDelete, "/v1/set/set/member", "", http.StatusOK, ""},
+		{http.MethodGet, "/v1/set/set/member", "", http.StatusNotFound, ""},
+
+		{http.MethodPut, "/v1/zset/zset/a", "2.5", http.StatusCreated, ""},
+		{http.MethodPut, "/v1/zset/zset/b", "1", http.StatusCreated, ""},
+		{http.MethodPut, "/v1/zset/zset/c", "3", http.StatusCreated, ""},
+		{http.MethodPut, "/v1/zset/zset/c", "0", http.StatusOK, ""},
+		{http.MethodPut, "/v1/zset/zset/d", "score", http.StatusBadRequest, ""},
+		{http.MethodGet, "/v1/zset/zset/a", "", http.StatusOK, `{"member":"a","score":2.5,"rank":2}` + "\n"},
+		{http.MethodGet, "/v1/zset/zset?start=1", "", http.StatusOK, `[{"member":"b","score":1},{"member":"a","score":2.5}]` + "\n"},
+		{http.MethodGet, "/v1/zset/zset?max=1", "", http.StatusOK, `[{"member":"c","score":0},{"member":"b","score":1}]` + "\n"},
+		{http.MethodPost, "/v1/zset/zset/popmin", "", http.StatusOK, `{"member":"c","score":0}` + "\n"},
+		{http.MethodDelete, "/v1/zset/zset/b", "", http.StatusOK, ""},
+		{http.MethodGet, "/v1/zset/zset/b", "", http.StatusNotFound, ""},
+
+		{http.MethodGet, "/v1/list", "", http.StatusConflict, ""},
+		{http.MethodGet, "/v1/set/list", "", http.StatusConflict, ""},
+	}
+
+	for _, step := range steps {
+		code, _, body, err := a.Request(step.method, step.path, step.body, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != step.code {
+			t.Fatalf("%s %s: got status %d, want %d", step.method, step.path, code, step.code)
+		}
+		if step.want != "" && body != step.want {
+			t.Fatalf("%s %s: got %q, want %q", step.method, step.path, body, step.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	a := tests.NewApp("../../main.go").
+		WithPort("9995").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin"))
+
+	a.Start()
+	defer a.Stop()
+
+	want := map[string]string{"user:1": "a", "user:2": "b", "user:3": "c", "user:4": "d", "user:5": "e"}
+	for key, value := range want {
+		if err := a.PutRequest(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.PutRequest("other", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	cursor := ""
+
+	for {
+		code, _, body, err := a.Request(http.MethodGet, "/v1?prefix=user:&limit=2&values&cursor="+cursor, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", code, http.StatusOK)
+		}
+
+		var page struct {
+			Keys   []string
+			Values map[string]string
+			Next   string
+		}
+
+		if err = json.Unmarshal([]byte(body), &page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Keys) > 2 {
+			t.Fatalf("got %d keys, limit is 2", len(page.Keys))
+		}
+
+		maps.Copy(got, page.Values)
+
+		if page.Next == "" {
+			break
+		}
+
+		cursor = page.Next
+	}
+
+	if !maps.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	code, _, _, err := a.Request(http.MethodGet, "/v1?cursor=broken", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	a := tests.NewApp("../../main.go").
+		WithPort("9996").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin"))
+
+	a.Start()
+	defer a.Stop()
+
+	resp, err := a.Stream("/v1/watch/config:?prefix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("got Content-Type %q, want text/event-stream", ct)
+	}
+
+	for _, key := range []string{"other", "config:a"} {
+		if err = a.PutRequest(key, "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = a.DeleteRequest("config:a"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"id: 2", "event: put", `data: {"key":"config:a","value":"value"}`, "",
+		"id: 3", "event: delete", `data: {"key":"config:a"}`, "",
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	for _, line := range want {
+		if !lines.Scan() {
+			t.Fatalf("stream ended: %v", lines.Err())
+		}
+		if lines.Text() != line {
+			t.Fatalf("got line %q, want %q", lines.Text(), line)
+		}
+	}
+}
+
+func TestBinaryValues(t *testing.T) {
+	a := tests.NewApp("../../main.go").
+		WithPort("9997").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin")).
+		WithArg("max_body_size", "1024")
+
+	a.Start()
+	defer a.Stop()
+
+	value := string([]byte{0x89, 'P', 'N', 'G', 0, 0xff, '\r', '\n', 0x80})
+
+	code, _, _, err := a.Request(http.MethodPut, "/v1/thumbnail", value, map[string]string{"Content-Type": "image/png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusCreated {
+		t.Fatalf("got status %d, want %d", code, http.StatusCreated)
+	}
+
+	code, header, body, err := a.Request(http.MethodGet, "/v1/thumbnail", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusOK || body != value {
+		t.Fatalf("got %q with status %d, want %q", body, code, value)
+	}
+	if ct := header.Get("Content-Type"); ct != "image/png" {
+		t.Fatalf("got Content-Type %q, want image/png", ct)
+	}
+
+	//json string could not keep value which is not UTF-8
+	code, _, body, err = a.Request(http.MethodGet, "/v1?prefix=thumb&values", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var page struct {
+		ValuesBase64 map[string][]byte `json:"values_base64"`
+	}
+	if err = json.Unmarshal([]byte(body), &page); err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusOK || string(page.ValuesBase64["thumbnail"]) != value {
+		t.Fatalf("got %s with status %d, want base64 of %q", body, code, value)
+	}
+
+	txn, err := json.Marshal([]map[string]any{{"op": "put", "key": "copy", "value_base64": []byte(value)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, _, body, err = a.Request(http.MethodPost, "/v1/operation/txn", string(txn), nil); err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusOK {
+		t.Fatalf("got %s with status %d of transaction, want %d", body, code, http.StatusOK)
+	}
+
+	code, _, body, err = a.Request(http.MethodGet, "/v1/copy", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusOK || body != value {
+		t.Fatalf("got %q with status %d, want %q", body, code, value)
+	}
+
+	code, _, _, err = a.Request(http.MethodPut, "/v1/big", strings.Repeat("a", 1025), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("got status %d, want %d", code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	logs := filepath.Join(t.TempDir(), "logs.bin")
+
+	a := tests.NewApp("../../main.go").
+		WithPort("9998").
+		WithLogsPath(logs)
+
+	a.Start()
+
+	for _, r := range []request{{"first", "1"}, {"second", "2"}, {"first", "3"}} {
+		if err := a.PutRequest(r.key, r.value); err != nil {
+			a.Stop()
+			t.Fatal(err)
+		}
+	}
+
+	code, _, _, err := a.Request(http.MethodPost, "/v1/operation/snapshot", "", nil)
+	if err != nil || code != http.StatusOK {
+		a.Stop()
+		t.Fatalf("got status %d (%v), want %d", code, err, http.StatusOK)
+	}
+
+	if err = a.PutRequest("after", "4"); err != nil {
+		a.Stop()
+		t.Fatal(err)
+	}
+
+	//events are written to the file in background
+	time.Sleep(100 * time.Millisecond)
+	a.Stop()
+
+	if _, err = os.Stat(filepath.Join(logs, "snapshot")); err != nil {
+		t.Fatal(err)
+	}
+
+	a.Start()
+	defer a.Stop()
+
+	for _, r := range []request{{"first", "3"}, {"second", "2"}, {"after", "4"}} {
+		if err = a.CheckGetRequest(r.key, r.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPointInTimeRestore(t *testing.T) {
+	logs := filepath.Join(t.TempDir(), "logs.bin")
+
+	a := tests.NewApp("../../main.go").
+		WithPort("9999").
+		WithLogsPath(logs)
+
+	a.Start()
+
+	put := func(key string, value string) string {
+		code, header, _, err := a.Request(http.MethodPut, "/v1/"+key, value, nil)
+		if err != nil || code != http.StatusCreated {
+			a.Stop()
+			t.Fatalf("got status %d (%v), want %d", code, err, http.StatusCreated)
+		}
+
+		return strings.Trim(header.Get("ETag"), `"`)
+	}
+
+	first := put("key", "first")
+	second := put("key", "second")
+
+	if err := a.ClearRequest(); err != nil {
+		a.Stop()
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/v1/operation/restore/key", http.StatusNotFound, ""},
+		{http.MethodPost, "/v1/operation/restore?to=yesterday", http.StatusBadRequest, ""},
+		{http.MethodPost, "/v1/operation/restore?to=" + first, http.StatusCreated, ""},
+		{http.MethodGet, "/v1/operation/restore/key", http.StatusOK, "first"},
+		{http.MethodGet, "/v1/key", http.StatusNotFound, ""},
+		{http.MethodDelete, "/v1/operation/restore", http.StatusOK, ""},
+		{http.MethodPost, "/v1/operation/restore/promote", http.StatusNotFound, ""},
+		{http.MethodPost, "/v1/operation/restore?to=" + second, http.StatusCreated, ""},
+		{http.MethodPost, "/v1/operation/restore/promote", http.StatusOK, ""},
+		{http.MethodGet, "/v1/key", http.StatusOK, "second"},
+	}
+
+	for _, step := range steps {
+		code, _, body, err := a.Request(step.method, step.path, "", nil)
+		if err != nil || code != step.code || (step.body != "" && body != step.body) {
+			a.Stop()
+			t.Fatalf("%s %s: got status %d body %q (%v), want %d %q", step.method, step.path, code, body, err, step.code, step.body)
+		}
+	}
+
+	put("after", "promote")
+
+	//events are written to the file in background
+	time.Sleep(100 * time.Millisecond)
+	a.Stop()
+
+	//rollback on start is logged too, so it stays after the next restart
+	a.WithArg("restore_to", first)
+	a.Start()
+	a.Stop()
+
+	a = tests.NewApp("../../main.go").
+		WithPort("9999").
+		WithLogsPath(logs)
+
+	a.Start()
+	defer a.Stop()
+
+	if err := a.CheckGetRequest("key", "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.CheckNoSuchKey("after"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	a := tests.NewApp("../../main.go").
+		WithPort("10000").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin")).
+		WithArg("durability", "always")
+
+	a.Start()
+	defer a.Stop()
+
+	code, _, body, err := a.Request(http.MethodGet, "/v1/operation/health", "", nil)
+	if err != nil || code != http.StatusOK || !strings.Contains(body, `"status":"ok"`) {
+		t.Fatalf("got %q with status %d (%v), want ok", body, code, err)
+	}
+
+	code, _, _, err = a.Request(http.MethodPut, "/v1/key", "value", map[string]string{"X-Async": "true"})
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("got status %d (%v), want %d", code, err, http.StatusCreated)
+	}
+
+	code, _, body, err = a.Request(http.MethodGet, "/metrics", "", nil)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("got status %d (%v), want %d", code, err, http.StatusOK)
+	}
+	if !strings.Contains(body, "cache_read_only 0\n") {
+		t.Fatalf("got metrics %q, want cache_read_only 0", body)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	a := tests.NewApp("../../main.go").
+		WithPort("10001").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin"))
+
+	a.Start()
+	defer a.Stop()
+
+	for _, key := range []string{"a", "b"} {
+		if err := a.PutRequest(key, "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := a.Stream("/v1/changes?since=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("got Content-Type %q, want application/x-ndjson", ct)
+	}
+
+	if err = a.DeleteRequest("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	//logged change is followed by live one
+	lines := bufio.NewScanner(resp.Body)
+	for _, want := range []string{
+		`{"id":2,"key":"b","type":"put","value":"value"}`,
+		`{"id":3,"key":"a","type":"delete"}`,
+	} {
+		if !lines.Scan() {
+			t.Fatalf("stream ended: %v", lines.Err())
+		}
+
+		var change map[string]any
+		if err = json.Unmarshal(lines.Bytes(), &change); err != nil {
+			t.Fatal(err)
+		}
+		//time is not known, keys are sorted by marshal of the map
+		delete(change, "time")
+
+		got, _ := json.Marshal(change)
+		if string(got) != want {
+			t.Fatalf("got change %s, want %s", got, want)
+		}
+	}
+
+	//reconnected EventSource keeps "since" of the first request
+	sse, err := a.StreamWithHeader("/v1/changes?since=1", http.Header{
+		"Accept":        {"text/event-stream"},
+		"Last-Event-ID": {"2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sse.Body.Close()
+
+	lines = bufio.NewScanner(sse.Body)
+	if !lines.Scan() || lines.Text() != "id: 3" {
+		t.Fatalf("got line %q (%v), want the change after Last-Event-ID", lines.Text(), lines.Err())
+	}
+}
+
+func TestReplication(t *testing.T) {
+	leader := tests.NewApp("../../main.go").
+		WithPort("10002").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin"))
+
+	leader.Start()
+	defer leader.Stop()
+
+	if err := leader.PutRequest("before", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	follower := tests.NewApp("../../main.go").
+		WithPort("10003").
+		WithLogsPath(filepath.Join(t.TempDir(), "logs.bin")).
+		WithArg("leader", "http://127.0.0.1:10002")
+
+	follower.Start()
+	defer follower.Stop()
+
+	//change sent to the follower is forwarded to the leader
+	if err := follower.PutRequest("after", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	for _, key := range []string{"before", "after"} {
+		if err := follower.CheckGetRequest(key, "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	code, _, body, err := follower.Request(http.MethodGet, "/v1/operation/replication", "", nil)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("got status %d (%v), want %d", code, err, http.StatusOK)
+	}
+
+	var status map[string]any
+	if err = json.Unmarshal([]byte(body), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status["role"] != "follower" || status["connected"] != true || status["applied_id"] != 2.0 || status["lag_events"] != 0.0 {
+		t.Fatalf("got replication %s, want connected follower with applied change 2 and no lag", body)
+	}
+}
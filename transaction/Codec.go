@@ -0,0 +1,341 @@
+package transaction
+
+import (
+	"bufio"
+	"bytes"
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Codec writes and reads records of one file of the log, it is made from the header of
+// the file, so files written by any codec are read whatever format is configured
+type Codec interface {
+	// WriteHeader writes header of the new file, records are appended after it
+	WriteHeader(w io.Writer) error
+	// Write writes whole record of the event or nothing
+	Write(w io.Writer, e core.Event) error
+	// Read reads one record, binaryEvent.ErrEmptyFile means that reader is finished
+	// and binaryEvent.ErrTruncated means that it ended inside the record
+	Read(r *bufio.Reader) (core.Event, error)
+	// SetOffset sets offset of the next record after the header, codec of the file
+	// those records are already written appends to it from its end
+	SetOffset(offset int64)
+}
+
+// Format of records of new segments and snapshots, it is written in the header
+type Format byte
+
+const (
+	// BinaryFormat is the compact format of binaryEvent
+	BinaryFormat Format = iota
+	// GobFormat frames records as binary format does, so they are checked, compressed
+	// and encrypted the same way, but every record describes its type for gob
+	GobFormat
+	// JSONFormat writes JSON object per line, so the log could be read by people and
+	// grep, it could not be compressed or encrypted
+	JSONFormat
+)
+
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "binary":
+		return BinaryFormat, nil
+	case "gob":
+		return GobFormat, nil
+	case "json":
+		return JSONFormat, nil
+	default:
+		return 0, fmt.Errorf("unknown log format: %q, it should be binary, gob or json", name)
+	}
+}
+
+// jsonHeader is the first line of JSON file, it is not binary header,
+// so the file has only text
+const jsonHeader = `{"log":"CLOG","version":4,"format":"json"}` + "\n"
+
+// NewCodec returns codec of the file with the header, keys decrypt its records
+func NewCodec(h binaryEvent.Header, keys *binaryEvent.Keyring) (Codec, error) {
+	switch Format(h.Format) {
+	case JSONFormat:
+		if h.Compression != binaryEvent.NoCompression || h.KeyID != "" {
+			return nil, errors.New("json log could not be compressed or encrypted")
+		}
+
+		return jsonCodec{}, nil
+	case BinaryFormat:
+		read, err := binaryEvent.ReaderOf(h, keys)
+		if err != nil {
+			return nil, err
+		}
+
+		enc, err := h.Encoding(keys)
+		if err != nil {
+			return nil, err
+		}
+
+		return binaryCodec{header: h, encoding: enc, read: read}, nil
+	case GobFormat:
+		enc, err := h.Encoding(keys)
+		if err != nil {
+			return nil, err
+		}
+
+		return gobCodec{header: h, encoding: enc}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format: %d", h.Format)
+	}
+}
+
+// ReadHeader reads header of JSON file or binary one, ErrEmptyFile
+// and ErrTruncated of binaryEvent are returned for both of them
+func ReadHeader(r *bufio.Reader) (binaryEvent.Header, error) {
+	//legacy file starts with "{" if ID of the first event is 123,
+	//but type of event follows it, it is never a quote
+	if prefix, _ := r.Peek(2); string(prefix) != jsonHeader[:2] {
+		return binaryEvent.ReadHeader(r)
+	}
+
+	header, _ := r.Peek(len(jsonHeader))
+	if string(header) != jsonHeader {
+		if strings.HasPrefix(jsonHeader, string(header)) {
+			return binaryEvent.Header{}, fmt.Errorf("read header was failed: %w", binaryEvent.ErrTruncated)
+		}
+
+		return binaryEvent.Header{}, fmt.Errorf("unknown header of json log: %q", header)
+	}
+
+	_, err := r.Discard(len(jsonHeader))
+	return binaryEvent.Header{Version: binaryEvent.Version, Format: byte(JSONFormat)}, err
+}
+
+// headerSize is size of the header in the file
+func headerSize(h binaryEvent.Header) int64 {
+	if Format(h.Format) == JSONFormat {
+		return int64(len(jsonHeader))
+	}
+
+	return h.Size()
+}
+
+type binaryCodec struct {
+	header   binaryEvent.Header
+	encoding binaryEvent.Encoding
+	//read reads records of the version of the header
+	read binaryEvent.ReadFunc
+}
+
+func (c binaryCodec) WriteHeader(w io.Writer) error {
+	return binaryEvent.WriteHeader(w, c.header)
+}
+
+func (c binaryCodec) Write(w io.Writer, e core.Event) error {
+	return c.encoding.Write(w, e)
+}
+
+func (c binaryCodec) Read(r *bufio.Reader) (core.Event, error) {
+	return c.read(r)
+}
+
+func (c binaryCodec) SetOffset(offset int64) {
+	c.encoding.SetOffset(offset)
+}
+
+type gobCodec struct {
+	header   binaryEvent.Header
+	encoding binaryEvent.Encoding
+}
+
+func (c gobCodec) WriteHeader(w io.Writer) error {
+	return binaryEvent.WriteHeader(w, c.header)
+}
+
+// Write encodes every record by the new encoder, so records are independent
+// and the log could be appended after restart
+func (c gobCodec) Write(w io.Writer, e core.Event) error {
+	payload := bytes.NewBuffer(nil)
+	if err := gob.NewEncoder(payload).Encode(e); err != nil {
+		return fmt.Errorf("encode event was failed: %w", err)
+	}
+
+	return c.encoding.WriteRecord(w, payload.Bytes())
+}
+
+func (c gobCodec) Read(r *bufio.Reader) (e core.Event, err error) {
+	payload, err := c.encoding.ReadRecord(r)
+	if err != nil {
+		return e, err
+	}
+
+	if err = gob.NewDecoder(bytes.NewReader(payload)).Decode(&e); err != nil {
+		return e, fmt.Errorf("decode event was failed: %w", err)
+	}
+
+	return e, nil
+}
+
+func (c gobCodec) SetOffset(offset int64) {
+	c.encoding.SetOffset(offset)
+}
+
+type jsonCodec struct{}
+
+// JSONEvent keeps value as text if it is UTF-8, so it could be read and found by grep,
+// it is the record of json log and the change of the feed of the store
+type JSONEvent struct {
+	ID          uint64      `json:"id,omitempty"`
+	Time        int64       `json:"time,omitempty"`
+	Type        JSONType    `json:"type"`
+	Key         string      `json:"key,omitempty"`
+	Field       string      `json:"field,omitempty"`
+	Value       *string     `json:"value,omitempty"`
+	ValueBase64 string      `json:"value_base64,omitempty"`
+	ContentType string      `json:"content_type,omitempty"`
+	Deadline    int64       `json:"deadline,omitempty"`
+	Batch       []JSONEvent `json:"batch,omitempty"`
+}
+
+// JSONType is written and read as name of the type
+type JSONType core.EventType
+
+func (t JSONType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(core.EventName(core.EventType(t)))
+}
+
+func (t *JSONType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	eventType, err := core.ParseEventType(name)
+	*t = JSONType(eventType)
+
+	return err
+}
+
+// EncodeValue returns value as text if it is UTF-8, otherwise it is returned as base64
+func EncodeValue(value []byte) (*string, string) {
+	switch {
+	case len(value) == 0:
+		return nil, ""
+	case utf8.Valid(value):
+		text := string(value)
+		return &text, ""
+	default:
+		return nil, base64.StdEncoding.EncodeToString(value)
+	}
+}
+
+// DecodeValue returns value of EncodeValue, text is taken if it is set
+func DecodeValue(text *string, encoded string) ([]byte, error) {
+	if text != nil {
+		return []byte(*text), nil
+	}
+
+	if encoded == "" {
+		return nil, nil
+	}
+
+	value, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode value was failed: %w", err)
+	}
+
+	return value, nil
+}
+
+func ToJSON(e core.Event) JSONEvent {
+	j := JSONEvent{
+		ID:          e.ID,
+		Time:        e.Time,
+		Type:        JSONType(e.Type),
+		Key:         e.Key,
+		Field:       e.Field,
+		ContentType: e.ContentType,
+		Deadline:    e.Deadline,
+	}
+
+	j.Value, j.ValueBase64 = EncodeValue(e.Value)
+
+	for _, op := range e.Batch {
+		j.Batch = append(j.Batch, ToJSON(op))
+	}
+
+	return j
+}
+
+func FromJSON(j JSONEvent) (core.Event, error) {
+	e := core.Event{
+		ID:          j.ID,
+		Time:        j.Time,
+		Type:        core.EventType(j.Type),
+		Key:         j.Key,
+		Field:       j.Field,
+		ContentType: j.ContentType,
+		Deadline:    j.Deadline,
+	}
+
+	var err error
+	if e.Value, err = DecodeValue(j.Value, j.ValueBase64); err != nil {
+		return e, err
+	}
+
+	for _, op := range j.Batch {
+		event, err := FromJSON(op)
+		if err != nil {
+			return e, err
+		}
+
+		e.Batch = append(e.Batch, event)
+	}
+
+	return e, nil
+}
+
+func (jsonCodec) WriteHeader(w io.Writer) error {
+	_, err := io.WriteString(w, jsonHeader)
+	return err
+}
+
+// Write writes the line at once, json.Marshal escapes new lines inside strings
+func (jsonCodec) Write(w io.Writer, e core.Event) error {
+	line, err := json.Marshal(ToJSON(e))
+	if err != nil {
+		return fmt.Errorf("encode event was failed: %w", err)
+	}
+
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// SetOffset does nothing, lines do not depend on their place
+func (jsonCodec) SetOffset(int64) {}
+
+// Read reads one line, line without new line at the end is cut by crash
+func (jsonCodec) Read(r *bufio.Reader) (core.Event, error) {
+	line, err := r.ReadBytes('\n')
+	if len(line) == 0 && err == io.EOF {
+		return core.Event{}, binaryEvent.ErrEmptyFile
+	}
+	if err == io.EOF {
+		return core.Event{}, fmt.Errorf("read line was failed: %w", binaryEvent.ErrTruncated)
+	}
+	if err != nil {
+		return core.Event{}, err
+	}
+
+	var j JSONEvent
+	if err = json.Unmarshal(line, &j); err != nil {
+		return core.Event{}, fmt.Errorf("decode event was failed: %w", err)
+	}
+
+	return FromJSON(j)
+}
@@ -0,0 +1,324 @@
+package transaction
+
+import (
+	"bufio"
+	"bytes"
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// testHeaders are headers of every codec, with and without compression and encryption
+func testHeaders(t *testing.T) (map[string]binaryEvent.Header, *binaryEvent.Keyring) {
+	t.Helper()
+
+	keys, err := binaryEvent.ParseKeyring(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := map[string]binaryEvent.Header{"json": {Version: binaryEvent.Version, Format: byte(JSONFormat)}}
+
+	for _, format := range []Format{BinaryFormat, GobFormat} {
+		name := map[Format]string{BinaryFormat: "binary", GobFormat: "gob"}[format]
+
+		headers[name] = binaryEvent.Header{Version: binaryEvent.Version, Format: byte(format)}
+		headers[name+"/flate/encrypted"] = binaryEvent.Header{
+			Version:     binaryEvent.Version,
+			Format:      byte(format),
+			Compression: binaryEvent.Flate,
+			KeyID:       keys.Active(),
+		}
+	}
+
+	return headers, keys
+}
+
+var text = []string{"", "value", "new\nline", `"quoted" {json}`, "фыва ёё", "\x00\x01 zero", strings.Repeat("long ", 300)}
+
+// randomEvent returns event with fields those are kept by its type,
+// events of transaction have only bodies
+func randomEvent(rng *rand.Rand, inBatch bool) core.Event {
+	var e core.Event
+
+	if !inBatch {
+		e.ID, e.Time = rng.Uint64(), rng.Int63()-rng.Int63()
+	}
+
+	e.Type = core.EventType(rng.Intn(int(core.EventSnapshot) + 1))
+	if inBatch && e.Type == core.EventTxn {
+		e.Type = core.EventPut
+	}
+
+	e.Key = text[rng.Intn(len(text))]
+
+	if rng.Intn(2) == 0 {
+		e.Value = []byte(text[rng.Intn(len(text))])
+	} else {
+		e.Value = make([]byte, rng.Intn(64))
+		rng.Read(e.Value)
+	}
+
+	if core.HasField(e.Type) {
+		e.Field = text[rng.Intn(len(text))]
+	}
+	if core.HasContentType(e.Type) {
+		e.ContentType = text[rng.Intn(len(text))]
+	}
+	if core.HasDeadline(e.Type) {
+		e.Deadline = rng.Int63()
+	}
+
+	if e.Type == core.EventTxn {
+		for range rng.Intn(4) {
+			e.Batch = append(e.Batch, randomEvent(rng, true))
+		}
+	}
+
+	return e
+}
+
+// normalize makes empty value and batch nil, codecs do not tell them apart
+func normalize(e core.Event) core.Event {
+	if len(e.Value) == 0 {
+		e.Value = nil
+	}
+
+	batch := e.Batch
+	e.Batch = nil
+
+	for _, op := range batch {
+		e.Batch = append(e.Batch, normalize(op))
+	}
+
+	return e
+}
+
+// encodeEvents writes the file by the new codec of the header, it returns offsets
+// where every record ends
+func encodeEvents(t *testing.T, header binaryEvent.Header, keys *binaryEvent.Keyring, events []core.Event) ([]byte, []int) {
+	t.Helper()
+
+	header, err := header.Salted()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codec, err := NewCodec(header, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := codec.WriteHeader(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var ends []int
+
+	for _, e := range events {
+		if err := codec.Write(buf, e); err != nil {
+			t.Fatal(err)
+		}
+
+		ends = append(ends, buf.Len())
+	}
+
+	return buf.Bytes(), ends
+}
+
+// decodeEvents reads the file by the codec of its header until the first error
+func decodeEvents(data []byte, keys *binaryEvent.Keyring) ([]core.Event, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+
+	header, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := NewCodec(header, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []core.Event
+
+	for {
+		e, err := codec.Read(r)
+		if err != nil {
+			return events, err
+		}
+
+		events = append(events, normalize(e))
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	headers, keys := testHeaders(t)
+
+	for name, header := range headers {
+		roundTrip := func(seed int64) bool {
+			rng := rand.New(rand.NewSource(seed))
+
+			var want []core.Event
+			for range 1 + rng.Intn(16) {
+				want = append(want, normalize(randomEvent(rng, false)))
+			}
+
+			data, _ := encodeEvents(t, header, keys, want)
+
+			got, err := decodeEvents(data, keys)
+			if !errors.Is(err, binaryEvent.ErrEmptyFile) || !reflect.DeepEqual(got, want) {
+				t.Logf("%s: got %v (%v), want %v", name, got, err, want)
+				return false
+			}
+
+			return true
+		}
+
+		if err := quick.Check(roundTrip, nil); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestCodecTornRecord(t *testing.T) {
+	headers, keys := testHeaders(t)
+
+	for name, header := range headers {
+		torn := func(seed int64) bool {
+			rng := rand.New(rand.NewSource(seed))
+
+			events := []core.Event{normalize(randomEvent(rng, false)), normalize(randomEvent(rng, false))}
+			data, ends := encodeEvents(t, header, keys, events)
+
+			//record cut anywhere is torn, the record before it is read
+			cut := ends[0] + 1 + rng.Intn(ends[1]-ends[0]-1)
+
+			got, err := decodeEvents(data[:cut], keys)
+			if !errors.Is(err, binaryEvent.ErrTruncated) || !reflect.DeepEqual(got, events[:1]) {
+				t.Logf("%s: cut at %d of %d: got %v (%v)", name, cut, ends[1], got, err)
+				return false
+			}
+
+			return true
+		}
+
+		if err := quick.Check(torn, nil); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		//header is cut
+		data, _ := encodeEvents(t, header, keys, nil)
+		if _, err := decodeEvents(data[:len(data)-1], keys); !errors.Is(err, binaryEvent.ErrTruncated) {
+			t.Fatalf("%s: got error %v of torn header, want %v", name, err, binaryEvent.ErrTruncated)
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	var want []core.Event
+	id := uint64(0)
+
+	//format is changed between restarts, files keep their own one
+	for _, format := range []Format{JSONFormat, GobFormat, BinaryFormat} {
+		tl, err := NewLogger(path, Options{Bandwidth: 4, Format: format, SegmentSize: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tl.Start()
+
+		for range 2 {
+			id++
+			if err = <-tl.WriteEvent(jsonPut(id, 1)); err != nil {
+				t.Fatal(err)
+			}
+
+			want = append(want, jsonPut(id, 1))
+		}
+
+		if err = tl.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Shutdown(context.Background())
+
+	if got := readAllEvents(t, tl); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	formats := map[string]Format{}
+	for _, name := range dirNames(t, path) {
+		header, err := readFileHeader(filepath.Join(path, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formats[name] = Format(header.Format)
+	}
+
+	wantFormats := map[string]Format{
+		filepath.Base(segmentPath(path, 1)): JSONFormat,
+		filepath.Base(segmentPath(path, 2)): JSONFormat,
+		filepath.Base(segmentPath(path, 3)): GobFormat,
+		filepath.Base(segmentPath(path, 4)): GobFormat,
+		filepath.Base(segmentPath(path, 5)): BinaryFormat,
+		filepath.Base(segmentPath(path, 6)): BinaryFormat,
+	}
+	if !reflect.DeepEqual(formats, wantFormats) {
+		t.Fatalf("got formats %v, want %v", formats, wantFormats)
+	}
+
+	//json segment is text, so grep does not take it for binary file
+	data, err := os.ReadFile(segmentPath(path, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.IndexByte(data, 0) != -1 || !bytes.Contains(data, []byte(`"key":"user:1"`)) {
+		t.Fatalf("json segment is not text: %q", data)
+	}
+
+	if _, err = NewLogger(filepath.Join(t.TempDir(), "logs.bin"), Options{Bandwidth: 4, Format: JSONFormat, Compression: binaryEvent.Flate}); err == nil {
+		t.Fatal("got no error of compressed json log")
+	}
+}
+
+func TestJSONTypes(t *testing.T) {
+	var line bytes.Buffer
+	if err := (jsonCodec{}).Write(&line, core.Event{ID: 1, Type: core.EventPutWithTTL, Key: "key", Deadline: 42}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(line.String(), `"type":"put-ttl"`) {
+		t.Fatalf("got record %q, want type by name", line.String())
+	}
+
+	events, err := decodeEvents([]byte(jsonHeader+line.String()), nil)
+	if !errors.Is(err, binaryEvent.ErrEmptyFile) {
+		t.Fatal(err)
+	}
+
+	if want := []core.Event{{ID: 1, Type: core.EventPutWithTTL, Key: "key", Deadline: 42}}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("got %v, want %v", events, want)
+	}
+
+	//type is never a number
+	if _, err = decodeEvents([]byte(jsonHeader+`{"id":2,"type":0,"key":"key"}`+"\n"), nil); err == nil {
+		t.Fatal("got no error of numeric type")
+	}
+}
@@ -0,0 +1,181 @@
+package transaction
+
+import (
+	"bufio"
+	"bytes"
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// jsonPut is put of JSON document with the list of similar items
+func jsonPut(id uint64, items int) core.Event {
+	value := bytes.NewBufferString(fmt.Sprintf(`{"id":%d,"orders":[`, id))
+
+	for i := range items {
+		if i > 0 {
+			value.WriteByte(',')
+		}
+
+		fmt.Fprintf(value, `{"order":%d,"status":"delivered","currency":"EUR","items":[{"sku":"A-%d","count":1}]}`, i, i%7)
+	}
+
+	value.WriteString("]}")
+
+	return core.Event{ID: id, Type: core.EventPut, Key: fmt.Sprintf("user:%d", id), Value: value.Bytes()}
+}
+
+func TestCompression(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4, Compression: binaryEvent.Flate})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl.Start()
+
+	var want []core.Event
+	for id := uint64(1); id <= 5; id++ {
+		tl.WriteEvent(jsonPut(id, 2))
+		want = append(want, jsonPut(id, 2))
+	}
+
+	if err = <-tl.(*FileLogger).Compact([]core.Event{{Type: core.EventSnapshot, ID: 5}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tl.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	//compression is changed, the active segment keeps its own one and is still appended
+	tl, err = NewLogger(path, Options{Bandwidth: 4, Compression: binaryEvent.NoCompression, SegmentSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl.Start()
+
+	for id := uint64(6); id <= 8; id++ {
+		tl.WriteEvent(jsonPut(id, 2))
+		want = append(want, jsonPut(id, 2))
+	}
+
+	if err = tl.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err = NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Shutdown(context.Background())
+
+	want = append([]core.Event{{Type: core.EventSnapshot, ID: 5}}, want[5:]...)
+	if got := readAllEvents(t, tl); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	compressions := map[string]binaryEvent.Compression{}
+	for _, name := range dirNames(t, path) {
+		file, err := os.Open(filepath.Join(path, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		header, err := binaryEvent.ReadHeader(bufio.NewReader(file))
+		_ = file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		compressions[name] = header.Compression
+	}
+
+	wantCompressions := map[string]binaryEvent.Compression{
+		"snapshot":                          binaryEvent.Flate,
+		filepath.Base(segmentPath(path, 6)): binaryEvent.Flate,
+		filepath.Base(segmentPath(path, 7)): binaryEvent.NoCompression,
+		filepath.Base(segmentPath(path, 8)): binaryEvent.NoCompression,
+	}
+	if !reflect.DeepEqual(compressions, wantCompressions) {
+		t.Fatalf("got compressions %v, want %v", compressions, wantCompressions)
+	}
+}
+
+// BenchmarkRestore reads the log of JSON values, disk-bytes is size of the log.
+// Records are compressed one by one, so small values are not compressed well
+func BenchmarkRestore(b *testing.B) {
+	const count = 10000
+
+	for _, bench := range []struct {
+		name  string
+		items int
+	}{
+		{"small", 1},
+		{"document", 20},
+	} {
+		for _, name := range []string{"none", "flate"} {
+			b.Run(bench.name+"/"+name, func(b *testing.B) {
+				benchmarkRestore(b, name, count, bench.items)
+			})
+		}
+	}
+}
+
+func benchmarkRestore(b *testing.B, name string, count int, items int) {
+	compression, err := binaryEvent.ParseCompression(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	path := filepath.Join(b.TempDir(), "logs.bin")
+
+	tl, err := NewLogger(path, Options{Bandwidth: 64, Compression: compression})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	tl.Start()
+
+	for id := uint64(1); id <= uint64(count); id++ {
+		tl.WriteEvent(jsonPut(id, items))
+	}
+
+	if err = tl.Shutdown(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+
+	size := int64(0)
+	for _, name := range dirNames(b, path) {
+		info, err := os.Stat(filepath.Join(path, name))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		size += info.Size()
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		read := 0
+
+		events, errs := tl.ReadEvents()
+		for range events {
+			read++
+		}
+
+		if err = <-errs; err != nil || read != count {
+			b.Fatalf("read %d events (%v), want %d", read, err, count)
+		}
+	}
+
+	b.ReportMetric(float64(size), "disk-bytes")
+}
@@ -0,0 +1,112 @@
+package transaction
+
+import (
+	"bufio"
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Convert rewrites the snapshot and segments of the log by format, compression and key
+// of the header, keys decrypt the old files and encrypt the new ones. It should not be
+// called while the log is open, interrupted conversion is finished by calling it again
+func Convert(path string, h binaryEvent.Header, keys *binaryEvent.Keyring) error {
+	h.Version = binaryEvent.Version
+
+	if _, err := NewCodec(h, keys); err != nil {
+		return err
+	}
+
+	_, err := rewriteLog(path, keys, "converted", func(binaryEvent.Header) binaryEvent.Header {
+		return h
+	})
+
+	return err
+}
+
+// rewriteLog rewrites every file of the log which header is changed by to,
+// it returns files of the log those are rewritten or kept
+func rewriteLog(path string, keys *binaryEvent.Keyring, action string, to func(binaryEvent.Header) binaryEvent.Header) ([]string, error) {
+	if err := Migrate(path, keys); err != nil {
+		return nil, err
+	}
+
+	files, err := LogFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if err = rewriteFile(file, keys, action, to); err != nil {
+			return nil, fmt.Errorf("rewrite %s was failed: %w", file, err)
+		}
+	}
+
+	return files, syncDir(path)
+}
+
+func rewriteFile(path string, keys *binaryEvent.Keyring, action string, to func(binaryEvent.Header) binaryEvent.Header) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+
+	header, err := ReadHeader(r)
+	if errors.Is(err, binaryEvent.ErrEmptyFile) || errors.Is(err, binaryEvent.ErrTruncated) {
+		//torn header is truncated by recovery
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	//salt is new in every written file, so it is not a change
+	changed := to(header)
+	changed.Salt = header.Salt
+
+	if changed == header {
+		return nil
+	}
+
+	codec, err := NewCodec(header, keys)
+	if err != nil {
+		return err
+	}
+
+	var events []core.Event
+
+	for {
+		e, err := codec.Read(r)
+		if errors.Is(err, binaryEvent.ErrEmptyFile) {
+			break
+		}
+		if errors.Is(err, binaryEvent.ErrTruncated) {
+			fmt.Printf("warning: torn record at the end of %s is dropped: %v\n", path, err)
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		events = append(events, e)
+	}
+
+	tmp := path + ".rewriting"
+	if err = writeFile(tmp, events, changed, keys); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is %s\n", path, action)
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package transaction
+
+import (
+	"fmt"
+	"os"
+)
+
+// Durability tells when written events are synced to the disk
+type Durability int
+
+const (
+	// NoSync leaves flushing to the OS, written events could be lost on power loss
+	NoSync Durability = iota
+	// SyncInterval syncs the log every Options.SyncInterval if something was written
+	SyncInterval
+	// SyncAlways syncs every write, events those are queued together are synced at once
+	SyncAlways
+)
+
+func ParseDurability(name string) (Durability, error) {
+	switch name {
+	case "none":
+		return NoSync, nil
+	case "interval":
+		return SyncInterval, nil
+	case "always":
+		return SyncAlways, nil
+	default:
+		return 0, fmt.Errorf("unknown durability: %q, it should be none, interval or always", name)
+	}
+}
+
+// commit writes the record with records those are already queued after it,
+// so with SyncAlways concurrent writers wait for one sync instead of one per event
+func (tl *FileLogger) commit(first record, records <-chan record, errs chan<- error) {
+	batch := []record{first}
+
+collect:
+	for len(batch) < cap(records) {
+		select {
+		case r, ok := <-records:
+			if !ok {
+				break collect
+			}
+
+			batch = append(batch, r)
+		default:
+			break collect
+		}
+	}
+
+	var written []record
+
+	//events are done only when they are synced, so Wait and their acks mean that
+	//they are durable, acks of events those were not written get the write error
+	done := func() {
+		var err error
+		if tl.opts.Durability == SyncAlways && len(written) != 0 {
+			if err = tl.sync(); err != nil {
+				report(errs, err)
+			}
+		}
+
+		for _, r := range written {
+			r.done <- err
+			tl.wg.Done()
+		}
+
+		written = written[:0]
+	}
+
+	for _, r := range batch {
+		if r.state != nil {
+			done()
+			tl.compact(r)
+			continue
+		}
+
+		if r.flush {
+			written = append(written, r)
+			continue
+		}
+
+		if err := tl.write(r.event); err != nil {
+			report(errs, err)
+			r.done <- err
+			tl.wg.Done()
+			continue
+		}
+
+		tl.unsynced = true
+		written = append(written, r)
+	}
+
+	done()
+}
+
+// sync is called by the writer goroutine or after it is stopped
+func (tl *FileLogger) sync() error {
+	if !tl.unsynced {
+		return nil
+	}
+
+	if err := tl.file.Sync(); err != nil {
+		return fmt.Errorf("sync log was failed: %w", err)
+	}
+
+	tl.unsynced = false
+
+	return nil
+}
+
+// syncDir makes created and renamed files of the directory durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
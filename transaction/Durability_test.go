@@ -0,0 +1,112 @@
+package transaction
+
+import (
+	"cache/core"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var durabilities = []struct {
+	name       string
+	durability Durability
+}{
+	{"none", NoSync},
+	{"interval", SyncInterval},
+	{"always", SyncAlways},
+}
+
+func TestDurability(t *testing.T) {
+	for _, d := range durabilities {
+		if parsed, err := ParseDurability(d.name); err != nil || parsed != d.durability {
+			t.Fatalf("got %v (%v), want %v", parsed, err, d.durability)
+		}
+
+		path := filepath.Join(t.TempDir(), "logs.bin")
+		opts := Options{Bandwidth: 4, Durability: d.durability, SyncInterval: time.Millisecond, SegmentSize: 64}
+
+		tl, err := NewLogger(path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tl.Start()
+
+		var want []core.Event
+		var acks []<-chan error
+		for id := uint64(1); id <= 20; id++ {
+			acks = append(acks, tl.WriteEvent(put(id)))
+			want = append(want, put(id))
+		}
+
+		for _, ack := range acks {
+			if err = <-ack; err != nil {
+				t.Fatalf("%s: %v", d.name, err)
+			}
+		}
+
+		if err = tl.Shutdown(context.Background()); err != nil {
+			t.Fatalf("%s: %v", d.name, err)
+		}
+
+		if err = <-tl.WriteEvent(put(21)); !errors.Is(err, ErrShutdown) {
+			t.Fatalf("%s: got error %v, want %v", d.name, err, ErrShutdown)
+		}
+
+		tl, err = NewLogger(path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := readAllEvents(t, tl); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %v, want %v", d.name, got, want)
+		}
+
+		if err = tl.Shutdown(context.Background()); err != nil {
+			t.Fatalf("%s: %v", d.name, err)
+		}
+	}
+
+	if _, err := ParseDurability("sometimes"); err == nil {
+		t.Fatal("got no error of unknown durability")
+	}
+}
+
+// BenchmarkDurability writes events from parallel writers, with always
+// durability writers those wait together share one sync
+func BenchmarkDurability(b *testing.B) {
+	for _, d := range durabilities {
+		b.Run(d.name, func(b *testing.B) {
+			tl, err := NewLogger(filepath.Join(b.TempDir(), "logs.bin"), Options{
+				Bandwidth:    64,
+				Durability:   d.durability,
+				SyncInterval: 10 * time.Millisecond,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			tl.Start()
+			e := core.Event{Type: core.EventPut, Key: "key", Value: make([]byte, 128)}
+
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := <-tl.WriteEvent(e); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+
+			b.StopTimer()
+
+			if err = tl.Shutdown(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package transaction
+
+import (
+	"cache/transaction/binaryEvent"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// KeysEnv keeps the keyring if there is no key file, its format is described in binaryEvent.ParseKeyring
+const KeysEnv = "CACHE_LOG_KEYS"
+
+// LoadKeyring reads the keyring from the file or from KeysEnv if the path is empty,
+// nil is returned if there is neither of them, so the log is not encrypted
+func LoadKeyring(path string) (*binaryEvent.Keyring, error) {
+	text := os.Getenv(KeysEnv)
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read key file was failed: %w", err)
+		}
+
+		text = string(data)
+	}
+
+	if text == "" {
+		return nil, nil
+	}
+
+	return binaryEvent.ParseKeyring(text)
+}
+
+// Reencrypt rewrites the snapshot and segments of the log those are not encrypted by
+// the active key of the keyring, so older keys could be removed. It should not be
+// called while the log is open, every file is replaced by the new one separately,
+// so interrupted re-encryption is finished by calling it again
+func Reencrypt(path string, keys *binaryEvent.Keyring) error {
+	if keys == nil {
+		return errors.New("keyring is required to re-encrypt the log")
+	}
+
+	files, err := rewriteLog(path, keys, "re-encrypted", func(h binaryEvent.Header) binaryEvent.Header {
+		h.KeyID = keys.Active()
+		return h
+	})
+	if err != nil {
+		return err
+	}
+
+	//backups of migration and quarantined records are kept as they were written
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if p := filepath.Join(path, entry.Name()); !slices.Contains(files, p) {
+			fmt.Printf("warning: %s is not re-encrypted, remove it if it is not needed\n", p)
+		}
+	}
+
+	return nil
+}
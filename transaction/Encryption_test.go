@@ -0,0 +1,184 @@
+package transaction
+
+import (
+	"bytes"
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	oldKey = "old:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+	newKey = "new:HxwdHhsaGRgXFhUUExIREA8ODQwLCgkIBwYFBAMCAQA="
+)
+
+// keyIDs returns key ID of every file of the log
+func keyIDs(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	ids := map[string]string{}
+	for _, name := range dirNames(t, path) {
+		header, err := readFileHeader(filepath.Join(path, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids[name] = header.KeyID
+	}
+
+	return ids
+}
+
+func TestEncryption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	t.Setenv(KeysEnv, oldKey)
+
+	old, err := LoadKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4, Keys: old})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl.Start()
+
+	for id := uint64(1); id <= 5; id++ {
+		tl.WriteEvent(jsonPut(id, 1))
+	}
+
+	if err = <-tl.(*FileLogger).Compact([]core.Event{{Type: core.EventSnapshot, ID: 5}, jsonPut(5, 1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tl.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range dirNames(t, path) {
+		data, err := os.ReadFile(filepath.Join(path, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("user:")) || bytes.Contains(data, []byte("delivered")) {
+			t.Fatalf("%s has plain event", name)
+		}
+	}
+
+	//the new key is active, the active segment keeps the old one
+	keyFile := filepath.Join(t.TempDir(), "keys")
+	if err = os.WriteFile(keyFile, []byte(oldKey+"\n"+newKey+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := LoadKeyring(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err = NewLogger(path, Options{Bandwidth: 4, Keys: rotated, SegmentSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl.Start()
+
+	for id := uint64(6); id <= 7; id++ {
+		if err = <-tl.WriteEvent(jsonPut(id, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []core.Event{{Type: core.EventSnapshot, ID: 5}, jsonPut(5, 1), jsonPut(6, 1), jsonPut(7, 1)}
+	if got := readAllEvents(t, tl); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if err = tl.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	wantIDs := map[string]string{
+		"snapshot":                          "old",
+		filepath.Base(segmentPath(path, 6)): "old",
+		filepath.Base(segmentPath(path, 7)): "new",
+	}
+	if got := keyIDs(t, path); !reflect.DeepEqual(got, wantIDs) {
+		t.Fatalf("got key IDs %v, want %v", got, wantIDs)
+	}
+
+	//the log could not be opened without the old key until it is re-encrypted
+	current, err := binaryEvent.ParseKeyring(newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewLogger(path, Options{Bandwidth: 4, Keys: current}); !errors.Is(err, binaryEvent.ErrUnknownKey) {
+		t.Fatalf("got error %v, want %v", err, binaryEvent.ErrUnknownKey)
+	}
+
+	if err = Reencrypt(path, rotated); err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range wantIDs {
+		wantIDs[name] = "new"
+	}
+	if got := keyIDs(t, path); !reflect.DeepEqual(got, wantIDs) {
+		t.Fatalf("got key IDs %v after re-encryption, want %v", got, wantIDs)
+	}
+
+	tl, err = NewLogger(path, Options{Bandwidth: 4, Keys: current})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Shutdown(context.Background())
+
+	if got := readAllEvents(t, tl); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v after re-encryption, want %v", got, want)
+	}
+}
+
+func TestWrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	keys, err := binaryEvent.ParseKeyring(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeEvents(t, path, put(1), put(2))
+
+	//the log is encrypted by the key with the same ID, but other bytes
+	if err = Reencrypt(path, keys); err != nil {
+		t.Fatal(err)
+	}
+
+	wrong, err := binaryEvent.ParseKeyring("old:HxwdHhsaGRgXFhUUExIREA8ODQwLCgkIBwYFBAMCAQA=")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the log is not quarantined, because its records are not broken
+	if _, err = NewLogger(path, Options{Bandwidth: 4, Keys: wrong, Corruption: QuarantineCorruption}); !errors.Is(err, binaryEvent.ErrDecrypt) {
+		t.Fatalf("got error %v, want %v", err, binaryEvent.ErrDecrypt)
+	}
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4, Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Shutdown(context.Background())
+
+	if got := readAllEvents(t, tl); !reflect.DeepEqual(got, []core.Event{put(1), put(2)}) {
+		t.Fatalf("got %v, want events 1-2", got)
+	}
+}
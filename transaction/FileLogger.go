@@ -1,6 +1,7 @@
 package transaction
 
 import (
+	"bufio"
 	"cache/core"
 	"cache/transaction/binaryEvent"
 	"context"
@@ -8,42 +9,250 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+var ErrShutdown = errors.New("logger is shut down")
+
 type FileLogger struct {
-	wg         *sync.WaitGroup
-	file       io.ReadWriteCloser
-	events     chan<- core.Event
-	bandwidth  int
-	currentID  uint64
+	wg *sync.WaitGroup
+	//path is the directory of the log, its layout is described in Segment.go
+	path string
+	opts Options
+	//file is the active segment, older events are in the snapshot and previous segments,
+	//it and fields of the active segment are changed only by the writer goroutine
+	file *os.File
+	//codec and headerSize are of the header of the active segment
+	codec        Codec
+	headerSize   int64
+	segmentSize  int64
+	segmentStart time.Time
+	//unsynced tells that something was written to the active segment since the last sync
+	unsynced bool
+	//size of the log written since the last snapshot
+	size atomic.Int64
+	//generation is held by readers while they open the snapshot and segments and by
+	//compaction while it replaces the snapshot and removes covered segments, so readers
+	//never get the old snapshot with segments of the new one
+	generation sync.RWMutex
+	records    chan<- record
+	//stopped is closed when the writer goroutine is finished
+	stopped chan struct{}
+	//mu guards inShutdown together with adding to wg, so nothing is added
+	//after Shutdown starts to wait and records are never sent after they are closed
+	mu         sync.Mutex
 	inShutdown bool
 }
 
-func NewLogger(filename string, bandwidth int) (core.TransactionLogger, error) {
-	if filename == "" {
+// Options of FileLogger
+type Options struct {
+	//Bandwidth is how many events could wait to be written
+	Bandwidth  int
+	Corruption CorruptionPolicy
+	//SegmentSize in bytes and SegmentAge roll the log over to the new segment, zero means never
+	SegmentSize int64
+	SegmentAge  time.Duration
+	//RetainSegments is how many segments covered by the snapshot are kept
+	RetainSegments int
+	Durability     Durability
+	SyncInterval   time.Duration
+	//Compression of new segments and snapshots, the active segment keeps its own one
+	Compression binaryEvent.Compression
+	//Keys decrypt the log, new segments and snapshots are encrypted by the active key,
+	//nil means that they are not encrypted
+	Keys *binaryEvent.Keyring
+	//Format of records of new segments and snapshots
+	Format Format
+}
+
+// header of new segments and snapshots
+func (o Options) header() binaryEvent.Header {
+	return binaryEvent.Header{
+		Version:     binaryEvent.Version,
+		Format:      byte(o.Format),
+		Compression: o.Compression,
+		KeyID:       o.Keys.Active(),
+	}
+}
+
+// record is either event, state for snapshot or flush, they are written in one queue,
+// so snapshot is taken exactly at the point of the log where state was copied
+type record struct {
+	event core.Event
+	state []core.Event
+	//flush is acked when records queued before it are written
+	flush bool
+	done  chan<- error
+}
+
+// NewLogger opens the log in the directory, older layout and format are upgraded,
+// torn record left by crash at the end of the log is truncated, broken record in
+// the middle of it is handled by the corruption policy
+func NewLogger(path string, opts Options) (core.TransactionLogger, error) {
+	if path == "" {
 		return &ZeroLogger{}, nil
 	}
 
-	if bandwidth < 1 {
+	if opts.Bandwidth < 1 {
 		return nil, errors.New("bandwidth should be at least 1")
 	}
 
-	file, err := os.OpenFile(filename, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0755)
+	if opts.Durability == SyncInterval && opts.SyncInterval <= 0 {
+		return nil, errors.New("sync interval should be positive")
+	}
+
+	if opts.Format == JSONFormat && (opts.Compression != binaryEvent.NoCompression || opts.Keys != nil) {
+		return nil, errors.New("json log could not be compressed or encrypted")
+	}
+
+	if err := Migrate(path, opts.Keys); err != nil {
+		return nil, err
+	}
+
+	segments, err := listSegments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	active := segmentPath(path, 1)
+	if len(segments) != 0 {
+		active = segments[len(segments)-1].path
+	}
+
+	file, err := os.OpenFile(active, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0755)
 	if err != nil {
 		return nil, err
 	}
 
-	return &FileLogger{file: file, wg: &sync.WaitGroup{}, bandwidth: bandwidth}, nil
+	var codec Codec
+	var header int64
+
+	if err = recoverLog(file, active, opts.Corruption, opts.Keys); err == nil {
+		codec, header, err = ensureHeader(file, opts.header(), opts.Keys)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := sizeSinceSnapshot(path, opts.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	tl := &FileLogger{
+		wg:           &sync.WaitGroup{},
+		path:         path,
+		opts:         opts,
+		file:         file,
+		codec:        codec,
+		headerSize:   header,
+		segmentSize:  info.Size() - header,
+		segmentStart: time.Now(),
+	}
+	tl.size.Store(size)
+
+	return tl, nil
 }
 
-func (tl *FileLogger) WriteEvent(t core.EventType, key string, value string) {
+// WriteEvent queues the event, ack is sent when it is written and synced if
+// durability is SyncAlways, with other durability it is not synced yet
+func (tl *FileLogger) WriteEvent(e core.Event) <-chan error {
+	return tl.enqueue(record{event: e})
+}
+
+// Flush acks when events those are already queued are written to the file,
+// so ReadEvents gets them
+func (tl *FileLogger) Flush() <-chan error {
+	return tl.enqueue(record{flush: true})
+}
+
+// Compact rotates the log and saves the snapshot in background,
+// rotated logs are removed when snapshot is saved
+func (tl *FileLogger) Compact(state []core.Event) <-chan error {
+	return tl.enqueue(record{state: state})
+}
+
+// enqueue sends the record to the writer goroutine, it is acked by ErrShutdown
+// if Shutdown is already started
+func (tl *FileLogger) enqueue(r record) <-chan error {
+	done := make(chan error, 1)
+	r.done = done
+
+	if !tl.add() {
+		done <- ErrShutdown
+		return done
+	}
+
+	tl.records <- r
+
+	return done
+}
+
+// add counts the record in wg, false means that logger is shut down
+func (tl *FileLogger) add() bool {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
 	if tl.inShutdown {
-		return
+		return false
 	}
 
 	tl.wg.Add(1)
-	tl.events <- core.Event{Type: t, Key: key, Value: value}
+
+	return true
+}
+
+func (tl *FileLogger) shutDown() bool {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	return tl.inShutdown
+}
+
+// probeSize is one page, so the probe fails if the disk has no space for one more page
+const probeSize = 4096
+
+// Check writes and syncs the probe file next to the log, so it tells if events
+// could be written and synced again after the log failed
+func (tl *FileLogger) Check() error {
+	if tl.shutDown() {
+		return ErrShutdown
+	}
+
+	probe := filepath.Join(tl.path, "probe")
+	defer os.Remove(probe)
+
+	return writeProbe(probe)
+}
+
+func writeProbe(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(make([]byte, probeSize))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (tl *FileLogger) LogSize() int64 {
+	return tl.size.Load()
 }
 
 func (tl *FileLogger) Wait() {
@@ -60,52 +269,227 @@ func (tl *FileLogger) ReadEvents() (<-chan core.Event, <-chan error) {
 		defer close(outError)
 		defer close(outEvent)
 
-		for {
-			event, err := binaryEvent.Read(tl.file)
+		if err := tl.readAll(outEvent); err != nil {
+			outError <- err
+		}
+	}()
 
-			if errors.Is(err, binaryEvent.ErrEmptyFile) {
-				return
-			}
-			if err != nil {
-				outError <- err
-				return
+	return outEvent, outError
+}
+
+// readAll sends the snapshot and events after it from segments in their order
+func (tl *FileLogger) readAll(out chan<- core.Event) error {
+	snapshot, segments, files, err := tl.openGeneration()
+	if err != nil {
+		return err
+	}
+	defer closeFiles(append(files, snapshot))
+
+	snapshotID := uint64(0)
+
+	if snapshot != nil {
+		err = readEvents(snapshot, tl.opts.Keys, func(e core.Event) {
+			if e.Type == core.EventSnapshot {
+				snapshotID = e.ID
 			}
 
-			outEvent <- event
+			out <- e
+		})
+		if err != nil {
+			return fmt.Errorf("read snapshot was failed: %w", err)
 		}
-	}()
+	}
 
-	return outEvent, outError
+	skipSnapshot := func(e core.Event) {
+		if e.ID > snapshotID {
+			out <- e
+		}
+	}
+
+	for i := range segments {
+		//retained segments are covered by the snapshot
+		if i+1 < len(segments) && segments[i+1].first <= snapshotID+1 {
+			continue
+		}
+
+		err = readEvents(files[i], tl.opts.Keys, skipSnapshot)
+
+		//the last segment could be read while events are written to it, record that
+		//is being written is cut, torn tail was truncated on open
+		if i == len(segments)-1 && errors.Is(err, binaryEvent.ErrTruncated) {
+			err = nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openGeneration opens the snapshot and segments together, compaction could replace
+// the snapshot and remove segments right after that, but opened files are still
+// readable. Snapshot is nil if there is no one
+func (tl *FileLogger) openGeneration() (*os.File, []segment, []*os.File, error) {
+	tl.generation.RLock()
+	defer tl.generation.RUnlock()
+
+	snapshot, err := os.Open(snapshotPath(tl.path))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil, fmt.Errorf("read snapshot was failed: %w", err)
+	}
+
+	segments, err := listSegments(tl.path)
+
+	files := make([]*os.File, 0, len(segments))
+
+	for i := 0; err == nil && i < len(segments); i++ {
+		var file *os.File
+		if file, err = os.Open(segments[i].path); err == nil {
+			files = append(files, file)
+		}
+	}
+
+	if err != nil {
+		closeFiles(append(files, snapshot))
+		return nil, nil, nil, err
+	}
+
+	return snapshot, segments, files, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		if file != nil {
+			_ = file.Close()
+		}
+	}
+}
+
+// ensureHeader writes the header to empty file, it returns codec of records those
+// are appended to the file and size of its header
+func ensureHeader(file *os.File, h binaryEvent.Header, keys *binaryEvent.Keyring) (Codec, int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if info.Size() != 0 {
+		//file is migrated before, so header has only current version
+		h, err = ReadHeader(bufio.NewReader(io.NewSectionReader(file, 0, info.Size())))
+	} else {
+		h, err = h.Salted()
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	codec, err := NewCodec(h, keys)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if info.Size() == 0 {
+		if err = codec.WriteHeader(file); err == nil {
+			err = file.Sync()
+		}
+	} else {
+		codec.SetOffset(info.Size() - headerSize(h))
+	}
+
+	return codec, headerSize(h), err
+}
+
+func readEvents(file io.Reader, keys *binaryEvent.Keyring, f func(e core.Event)) error {
+	//one reader for the whole file, binaryEvent.Read reuses it instead of
+	//wrapping file again and losing already buffered events
+	r := bufio.NewReader(file)
+
+	header, err := ReadHeader(r)
+	if errors.Is(err, binaryEvent.ErrEmptyFile) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	codec, err := NewCodec(header, keys)
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := codec.Read(r)
+
+		if errors.Is(err, binaryEvent.ErrEmptyFile) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		f(event)
+	}
 }
 
 func (tl *FileLogger) Start() <-chan error {
 	//buffer 16 means that 16 handlers can send event and do not wait when logger write event to file
-	events := make(chan core.Event, tl.bandwidth)
-	errs := make(chan error)
+	records := make(chan record, tl.opts.Bandwidth)
+	//errors are also sent to acks of their events, so caller could skip them
+	//and the writer goroutine never waits for the caller
+	errs := make(chan error, tl.opts.Bandwidth)
 
-	tl.events = events
+	tl.records = records
+	tl.stopped = make(chan struct{})
 
 	go func() {
 		defer close(errs)
-		//always read from events channel, Somebody who write to this channel is
+		defer close(tl.stopped)
+
+		//nil channel never ticks
+		var tick <-chan time.Time
+		if tl.opts.Durability == SyncInterval {
+			ticker := time.NewTicker(tl.opts.SyncInterval)
+			defer ticker.Stop()
+
+			tick = ticker.C
+		}
+
+		//always read from records channel, Somebody who write to this channel is
 		//responsible for closing it at the right time
-		for e := range events {
-			e.ID = tl.currentID
-			tl.currentID++
+		for {
+			select {
+			case r, ok := <-records:
+				if !ok {
+					return
+				}
 
-			if err := binaryEvent.WriteTo(tl.file, e); err != nil {
-				errs <- err
+				tl.commit(r, records, errs)
+			case <-tick:
+				if err := tl.sync(); err != nil {
+					report(errs, err)
+				}
 			}
-
-			tl.wg.Done()
 		}
 	}()
 
 	return errs
 }
 
+// report sends the error to errs of Start, it is dropped if buffer of errs is full
+func report(errs chan<- error, err error) {
+	select {
+	case errs <- err:
+	default:
+	}
+}
+
+// Shutdown waits for queued events, syncs them if durability is set and closes the log
 func (tl *FileLogger) Shutdown(ctx context.Context) error {
+	tl.mu.Lock()
 	tl.inShutdown = true
+	tl.mu.Unlock()
 
 	errs := make(chan error)
 
@@ -114,8 +498,16 @@ func (tl *FileLogger) Shutdown(ctx context.Context) error {
 
 		tl.Wait()
 
-		if tl.events != nil {
-			close(tl.events)
+		if tl.records != nil {
+			close(tl.records)
+			<-tl.stopped
+		}
+
+		if tl.opts.Durability != NoSync {
+			if err := tl.sync(); err != nil {
+				errs <- err
+				return
+			}
 		}
 
 		if err := tl.file.Close(); err != nil {
@@ -127,8 +519,10 @@ func (tl *FileLogger) Shutdown(ctx context.Context) error {
 	case <-ctx.Done():
 		return fmt.Errorf("shutdown logger was cancelled: %w", ctx.Err())
 	case err := <-errs:
-		return fmt.Errorf("shutdown logger was faild: %w", err)
-	default:
+		if err != nil {
+			return fmt.Errorf("shutdown logger was faild: %w", err)
+		}
+
 		return nil
 	}
 }
@@ -2,6 +2,16 @@ package transaction
 
 import (
 	"bytes"
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
 )
 
 type mockFile struct {
@@ -11,3 +21,181 @@ type mockFile struct {
 func (mf mockFile) Close() error {
 	return nil
 }
+
+func readAllEvents(t *testing.T, tl core.TransactionLogger) []core.Event {
+	t.Helper()
+
+	var got []core.Event
+
+	events, errs := tl.ReadEvents()
+	for e := range events {
+		got = append(got, e)
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	return got
+}
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl.Start()
+
+	for id := uint64(1); id <= 5; id++ {
+		tl.WriteEvent(core.Event{ID: id, Type: core.EventPut, Key: "key", Value: []byte{byte(id)}})
+	}
+
+	state := []core.Event{
+		{ID: 5, Type: core.EventSnapshot},
+		{ID: 5, Type: core.EventPut, Key: "key", Value: []byte{5}},
+	}
+
+	if err = <-tl.(core.Compactor).Compact(state); err != nil {
+		t.Fatal(err)
+	}
+
+	after := core.Event{ID: 6, Type: core.EventDelete, Key: "key"}
+	tl.WriteEvent(after)
+
+	tl.(*FileLogger).Wait()
+	if err = tl.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	//segment covered by snapshot is removed after snapshot is saved
+	if names := dirNames(t, path); !slices.Equal(names, []string{filepath.Base(segmentPath(path, 6)), "snapshot"}) {
+		t.Fatalf("got files %v", names)
+	}
+
+	tl, err = NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := readAllEvents(t, tl)
+	want := append(state, after)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func dirNames(t testing.TB, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+
+	return names
+}
+
+func writeEvents(t *testing.T, path string, events ...core.Event) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFile(path, events, binaryEvent.Header{}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func put(id uint64) core.Event {
+	return core.Event{ID: id, Type: core.EventPut, Key: strconv.FormatUint(id, 10)}
+}
+
+func TestReadSegments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	//snapshot at 3 was saved, the first segment is retained
+	writeEvents(t, snapshotPath(path), core.Event{ID: 3, Type: core.EventSnapshot})
+	writeEvents(t, segmentPath(path, 1), put(1), put(2), put(3))
+	writeEvents(t, segmentPath(path, 4), put(4), put(10))
+	writeEvents(t, segmentPath(path, 11), put(11))
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []core.Event{{ID: 3, Type: core.EventSnapshot}, put(4), put(10), put(11)}
+	if got := readAllEvents(t, tl); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl.Start()
+
+	checker := tl.(core.Checker)
+	if err = checker.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	//probe is removed, so it is never read as a part of the log
+	if names := dirNames(t, path); !slices.Equal(names, []string{filepath.Base(segmentPath(path, 1))}) {
+		t.Fatalf("got files %v, want only the segment", names)
+	}
+
+	if err = tl.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = checker.Check(); !errors.Is(err, ErrShutdown) {
+		t.Fatalf("got error %v, want %v", err, ErrShutdown)
+	}
+}
+
+func TestWriteDuringShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl.Start()
+
+	acks := make(chan (<-chan error), 100)
+	go func() {
+		defer close(acks)
+
+		for id := uint64(1); id <= 100; id++ {
+			acks <- tl.WriteEvent(put(id))
+		}
+	}()
+
+	if err = tl.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	//every write is either written before shutdown or rejected, it never panics
+	for ack := range acks {
+		if err = <-ack; err != nil && !errors.Is(err, ErrShutdown) {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package transaction
+
+import (
+	"bufio"
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// LogFiles returns the snapshot and segments of the log in order of their events,
+// path of one file is returned as it is, so files out of the log could be inspected
+func LogFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	segments, err := listSegments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+
+	if _, err = os.Stat(snapshotPath(path)); err == nil {
+		files = append(files, snapshotPath(path))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	for _, s := range segments {
+		files = append(files, s.path)
+	}
+
+	return files, nil
+}
+
+// ScanFile calls f for every record of the file with its offset, it returns header of the
+// file, offset after the last good record and error of the next one, nil if all of them
+// are good. Error of f stops scan, it is returned with offset of its record
+func ScanFile(path string, keys *binaryEvent.Keyring, f func(offset int64, e core.Event) error) (binaryEvent.Header, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return binaryEvent.Header{}, 0, err
+	}
+	defer file.Close()
+
+	counter := &countReader{Reader: file}
+	r := bufio.NewReader(counter)
+
+	header, err := ReadHeader(r)
+	if errors.Is(err, binaryEvent.ErrEmptyFile) {
+		return header, 0, nil
+	}
+	if err != nil {
+		return header, 0, err
+	}
+
+	codec, err := NewCodec(header, keys)
+	if err != nil {
+		return header, 0, err
+	}
+
+	offset, err := scanRecords(r, counter, codec, f)
+	return header, offset, err
+}
+
+// scanRecords reads records after the header, it returns offset after the last good
+// record and error of the next one, nil if reader is finished
+func scanRecords(r *bufio.Reader, counter *countReader, codec Codec, f func(offset int64, e core.Event) error) (int64, error) {
+	for {
+		offset := counter.n - int64(r.Buffered())
+
+		e, err := codec.Read(r)
+		if errors.Is(err, binaryEvent.ErrEmptyFile) {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+
+		if f != nil {
+			if err = f(offset, e); err != nil {
+				return offset, err
+			}
+		}
+	}
+}
+
+// truncatedPath keeps records dropped by Truncate, it is not a segment
+func truncatedPath(path string, offset int64) string {
+	return path + ".truncated-" + strconv.FormatInt(offset, 10)
+}
+
+// Truncate drops the record of the segment at offset and everything after it, later
+// segments included. Dropped records are moved to separate files as quarantine does,
+// it should not be called while the log is open. Keys decrypt records to check the offset
+func Truncate(segmentFile string, offset int64, keys *binaryEvent.Keyring) error {
+	dir := filepath.Dir(segmentFile)
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	i := 0
+	for i < len(segments) && filepath.Base(segments[i].path) != filepath.Base(segmentFile) {
+		i++
+	}
+	if i == len(segments) {
+		return fmt.Errorf("%s is not a segment of the log", segmentFile)
+	}
+
+	//offset should be start of the record, records after broken one are not known
+	found := false
+	_, last, err := ScanFile(segmentFile, keys, func(o int64, _ core.Event) error {
+		found = found || o == offset
+		return nil
+	})
+	if errors.Is(err, binaryEvent.ErrUnknownKey) || errors.Is(err, binaryEvent.ErrDecrypt) {
+		return fmt.Errorf("records of %s could not be checked: %w", segmentFile, err)
+	}
+	if !found && !(err != nil && last == offset) {
+		return fmt.Errorf("offset %d of %s is not start of a record", offset, segmentFile)
+	}
+
+	file, err := os.OpenFile(segmentFile, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err = quarantine(file, truncatedPath(segmentFile, offset), offset); err != nil {
+		return err
+	}
+
+	if err = file.Truncate(offset); err != nil {
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is truncated at offset %d, dropped records are moved to %s\n",
+		segmentFile, offset, truncatedPath(segmentFile, offset))
+
+	for _, s := range segments[i+1:] {
+		if err = os.Rename(s.path, truncatedPath(s.path, 0)); err != nil {
+			return err
+		}
+
+		fmt.Printf("%s is dropped, it is moved to %s\n", s.path, truncatedPath(s.path, 0))
+	}
+
+	return syncDir(dir)
+}
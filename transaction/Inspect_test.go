@@ -0,0 +1,140 @@
+package transaction
+
+import (
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeSegments writes events 1-6 to segments of two events
+func writeSegments(t *testing.T, path string) {
+	t.Helper()
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4, SegmentSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl.Start()
+
+	for id := uint64(1); id <= 6; id++ {
+		if err = <-tl.WriteEvent(put(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = tl.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+	writeSegments(t, path)
+
+	files, err := LogFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 6 {
+		t.Fatalf("got files %v, want 6 segments", files)
+	}
+
+	var offsets []int64
+	_, end, err := ScanFile(segmentPath(path, 3), nil, func(offset int64, e core.Event) error {
+		offsets = append(offsets, offset)
+		return nil
+	})
+	if err != nil || len(offsets) != 1 {
+		t.Fatalf("got offsets %v (%v), want one record", offsets, err)
+	}
+
+	if err = Truncate(segmentPath(path, 3), offsets[0]+1, nil); err == nil {
+		t.Fatal("got no error of offset inside the record")
+	}
+
+	if err = Truncate(segmentPath(path, 3), offsets[0], nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Shutdown(context.Background())
+
+	if got := readAllEvents(t, tl); !reflect.DeepEqual(got, []core.Event{put(1), put(2)}) {
+		t.Fatalf("got %v, want events 1-2", got)
+	}
+
+	//dropped records are kept out of the log
+	for _, name := range []string{
+		filepath.Base(truncatedPath(segmentPath(path, 3), offsets[0])),
+		filepath.Base(truncatedPath(segmentPath(path, 4), 0)),
+		filepath.Base(truncatedPath(segmentPath(path, 6), 0)),
+	} {
+		if _, err = os.Stat(filepath.Join(path, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dropped, err := os.ReadFile(truncatedPath(segmentPath(path, 3), offsets[0]))
+	if err != nil || int64(len(dropped)) != end-offsets[0] {
+		t.Fatalf("got %d dropped bytes (%v), want %d", len(dropped), err, end-offsets[0])
+	}
+}
+
+func TestConvert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+	writeSegments(t, path)
+
+	keys, err := binaryEvent.ParseKeyring(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Convert(path, binaryEvent.Header{Format: byte(JSONFormat), KeyID: keys.Active()}, keys); err == nil {
+		t.Fatal("got no error of encrypted json log")
+	}
+
+	to := binaryEvent.Header{Version: binaryEvent.Version, Format: byte(GobFormat), Compression: binaryEvent.Flate, KeyID: keys.Active()}
+	if err = Convert(path, to, keys); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := LogFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []core.Event
+
+	for _, file := range files {
+		header, _, err := ScanFile(file, keys, func(_ int64, e core.Event) error {
+			got = append(got, e)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		//every file has its own salt
+		header.Salt = to.Salt
+
+		if header != to {
+			t.Fatalf("%s: got header %+v, want %+v", file, header, to)
+		}
+	}
+
+	if want := []core.Event{put(1), put(2), put(3), put(4), put(5), put(6)}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if _, _, err = ScanFile(files[0], nil, nil); !errors.Is(err, binaryEvent.ErrUnknownKey) {
+		t.Fatalf("got error %v without keys, want %v", err, binaryEvent.ErrUnknownKey)
+	}
+}
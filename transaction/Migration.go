@@ -0,0 +1,174 @@
+package transaction
+
+import (
+	"bufio"
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// backupPath keeps file of the old format after migration, it is not a segment
+func backupPath(path string, version byte) string {
+	return fmt.Sprintf("%s.v%d", path, version)
+}
+
+// Migrate upgrades layout of the log to the directory and its snapshot and
+// segments of LegacyVersion to the current version of the format, old files
+// are kept with version suffix. Files written by development builds between
+// them are rejected instead of being migrated with broken events: the ones
+// without header by ErrNotLegacy and the ones with header of another version
+// by binaryEvent.ErrUnsupportedVersion
+func Migrate(path string, keys *binaryEvent.Keyring) error {
+	if path == "" {
+		return nil
+	}
+
+	if err := upgradeLayout(path); err != nil {
+		return fmt.Errorf("upgrade layout of %s was failed: %w", path, err)
+	}
+
+	segments, err := listSegments(path)
+	if err != nil {
+		return err
+	}
+
+	paths := []string{snapshotPath(path)}
+	for _, s := range segments {
+		paths = append(paths, s.path)
+	}
+
+	for _, p := range paths {
+		if err = migrateFile(p, keys); err != nil {
+			return fmt.Errorf("migrate %s was failed: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+func migrateFile(path string, keys *binaryEvent.Keyring) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+
+	header, err := ReadHeader(r)
+	if errors.Is(err, binaryEvent.ErrEmptyFile) || errors.Is(err, binaryEvent.ErrTruncated) {
+		//torn header is truncated by recovery
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if header.Version == binaryEvent.Version {
+		return nil
+	}
+
+	version := header.Version
+
+	read, err := binaryEvent.ReaderOf(header, keys)
+	if err != nil {
+		return err
+	}
+
+	var events []core.Event
+
+	for {
+		e, err := read(r)
+		if errors.Is(err, binaryEvent.ErrEmptyFile) {
+			break
+		}
+		if errors.Is(err, binaryEvent.ErrTruncated) {
+			fmt.Printf("warning: torn record at the end of %s is dropped by migration: %v\n", path, err)
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		events = append(events, e)
+	}
+
+	//LegacyVersion kept only low byte sums of IDs, so they repeat and go back,
+	//but every consumer of the log expects growing IDs
+	if version == binaryEvent.LegacyVersion {
+		renumbered := false
+
+		for i := range events {
+			renumbered = renumbered || events[i].ID != uint64(i+1)
+			events[i].ID = uint64(i + 1)
+		}
+
+		if renumbered {
+			fmt.Printf("warning: IDs of %s are renumbered from 1, versions of keys are changed\n", path)
+		}
+	}
+
+	tmp := path + ".migrating"
+	migrated := binaryEvent.Header{Version: binaryEvent.Version, Compression: header.Compression, KeyID: header.KeyID}
+
+	if err = writeFile(tmp, events, migrated, keys); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err = os.Rename(path, backupPath(path, version)); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is migrated from version %d to %d, the old file is kept in %s\n",
+		path, version, binaryEvent.Version, backupPath(path, version))
+
+	return nil
+}
+
+// writeFile writes events by codec of the header to the new file and syncs it
+func writeFile(path string, events []core.Event, h binaryEvent.Header, keys *binaryEvent.Keyring) error {
+	h.Version = binaryEvent.Version
+
+	h, err := h.Salted()
+	if err != nil {
+		return err
+	}
+
+	codec, err := NewCodec(h, keys)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	err = codec.WriteHeader(w)
+
+	for i := 0; err == nil && i < len(events); i++ {
+		err = codec.Write(w, events[i])
+	}
+
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,142 @@
+package transaction
+
+import (
+	"bytes"
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMigrateLegacyLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	//put of "key" with ID 1 and delete of it with ID 2 written by the first version,
+	//the last record is torn
+	legacy := []byte("\x01\x00\x01\x03\x00key\x05\x00value" + "\x02\x00\x00\x03\x00key\x00" + "\x03\x00\x01\x03")
+
+	if err := os.WriteFile(path, legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Shutdown(context.Background())
+
+	want := []core.Event{
+		{ID: 1, Type: core.EventPut, Key: "key", Value: []byte("value")},
+		{ID: 2, Type: core.EventDelete, Key: "key"},
+	}
+
+	if got := readAllEvents(t, tl); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	backup, err := os.ReadFile(backupPath(segmentPath(path, 1), 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backup, legacy) {
+		t.Fatal("backup differs from the legacy log")
+	}
+
+	//migrated log is not migrated again
+	if err = Migrate(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(backupPath(segmentPath(path, 1), binaryEvent.Version)); !os.IsNotExist(err) {
+		t.Fatalf("got %v, want no backup of the current version", err)
+	}
+}
+
+func TestMigrateLegacyIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	//IDs of the first version were sums of their bytes, so they repeat and go back to zero
+	legacy := []byte("\x05\x00\x01\x01\x00a\x01\x001" + "\x05\x00\x01\x01\x00b\x01\x002" + "\x00\x00\x01\x00a\x00")
+
+	if err := os.WriteFile(path, legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Shutdown(context.Background())
+
+	want := []core.Event{
+		{ID: 1, Type: core.EventPut, Key: "a", Value: []byte("1")},
+		{ID: 2, Type: core.EventPut, Key: "b", Value: []byte("2")},
+		{ID: 3, Type: core.EventDelete, Key: "a"},
+	}
+
+	if got := readAllEvents(t, tl); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestMigrateNotLegacy(t *testing.T) {
+	//builds between the first version and the header wrote uvarint numbers
+	//and then checksums without header
+	checksummed := bytes.NewBuffer(nil)
+	if err := binaryEvent.WriteTo(checksummed, put(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	logs := map[string][]byte{
+		"uvarint":     []byte("\x01\x01\x03key\x05value" + "\x02\x00\x03key\x00"),
+		"ttl":         []byte("\x01\x00\x03\x03\x00key\x05\x00value"),
+		"checksummed": checksummed.Bytes(),
+	}
+
+	for name, data := range logs {
+		path := filepath.Join(t.TempDir(), "logs.bin")
+
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := NewLogger(path, Options{Bandwidth: 4}); !errors.Is(err, binaryEvent.ErrNotLegacy) {
+			t.Fatalf("%s: got error %v, want %v", name, err, binaryEvent.ErrNotLegacy)
+		}
+
+		//rejected file is kept as it is
+		got, err := os.ReadFile(segmentPath(path, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("%s: file is changed", name)
+		}
+	}
+}
+
+func TestMigrateDevelopmentLog(t *testing.T) {
+	//headers of development builds: checksums, compression, key ID and format without salt
+	for _, header := range []string{"CLOG\x01", "CLOG\x02\x00", "CLOG\x03\x00\x00", "CLOG\x04\x00\x00\x00"} {
+		path := filepath.Join(t.TempDir(), "logs.bin")
+
+		old := bytes.NewBufferString(header)
+		if err := binaryEvent.WriteTo(old, put(1)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(segmentPath(path, 1), old.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := NewLogger(path, Options{Bandwidth: 4}); !errors.Is(err, binaryEvent.ErrUnsupportedVersion) {
+			t.Fatalf("%q: got error %v, want %v", header, err, binaryEvent.ErrUnsupportedVersion)
+		}
+	}
+}
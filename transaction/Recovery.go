@@ -0,0 +1,179 @@
+package transaction
+
+import (
+	"bufio"
+	"cache/transaction/binaryEvent"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+var ErrCorruptedLog = errors.New("log is corrupted")
+
+// CorruptionPolicy tells what to do with broken record in the middle of the log,
+// torn record at the end of the log is always truncated
+type CorruptionPolicy int
+
+const (
+	// FailOnCorruption refuses to open the log
+	FailOnCorruption CorruptionPolicy = iota
+	// QuarantineCorruption moves the log from the broken record to the end into
+	// separate file, events before the record are kept
+	QuarantineCorruption
+)
+
+func ParseCorruptionPolicy(name string) (CorruptionPolicy, error) {
+	switch name {
+	case "fail":
+		return FailOnCorruption, nil
+	case "quarantine":
+		return QuarantineCorruption, nil
+	default:
+		return 0, fmt.Errorf("unknown corruption policy: %q, it should be fail or quarantine", name)
+	}
+}
+
+// quarantinePath is not a segment, because its suffix is not a number
+func quarantinePath(path string, offset int64) string {
+	return path + ".corrupted-" + strconv.FormatInt(offset, 10)
+}
+
+// countReader counts bytes read from the file, bytes buffered by bufio.Reader are
+// subtracted from it to get offset of the next record
+type countReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+
+	return n, err
+}
+
+// lastRecord returns offset after the last good record and error of the next
+// one, torn tells if it is torn by crash: nothing but zeros is left after the
+// place where it was found broken, zeros are there if file was extended by
+// crashed write, but data was not written
+func lastRecord(file io.ReadSeeker, keys *binaryEvent.Keyring) (offset int64, torn bool, err error) {
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return 0, false, err
+	}
+
+	counter := &countReader{Reader: file}
+	r := bufio.NewReader(counter)
+
+	//file is migrated before, so header has only current version
+	header, err := ReadHeader(r)
+	if errors.Is(err, binaryEvent.ErrTruncated) {
+		return 0, true, err
+	}
+	if errors.Is(err, binaryEvent.ErrEmptyFile) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	codec, err := NewCodec(header, keys)
+	if err != nil {
+		return 0, false, err
+	}
+
+	offset, err = scanRecords(r, counter, codec, nil)
+	if err == nil {
+		return offset, false, nil
+	}
+
+	//record with right checksums is not broken, but the key is wrong,
+	//so the log is not truncated or quarantined by its policy
+	if errors.Is(err, binaryEvent.ErrDecrypt) {
+		return offset, false, err
+	}
+
+	zeros, zeroErr := onlyZeros(r)
+	if zeroErr != nil {
+		return offset, false, zeroErr
+	}
+
+	return offset, zeros, err
+}
+
+func onlyZeros(r io.ByteReader) (bool, error) {
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if b != 0 {
+			return false, nil
+		}
+	}
+}
+
+// recoverLog is called before anything is written to the log, torn record left
+// by crash is truncated, broken record in the middle is handled by the policy
+func recoverLog(file *os.File, path string, policy CorruptionPolicy, keys *binaryEvent.Keyring) error {
+	offset, torn, err := lastRecord(file, keys)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, binaryEvent.ErrDecrypt) {
+		return fmt.Errorf("record at offset %d of %s was not decrypted, the key could be wrong: %w", offset, path, err)
+	}
+
+	info, statErr := file.Stat()
+	if statErr != nil {
+		return statErr
+	}
+
+	lost := info.Size() - offset
+
+	switch {
+	case torn:
+		fmt.Printf("warning: torn record at offset %d of %s, %d bytes are truncated: %v\n", offset, path, lost, err)
+	case policy == QuarantineCorruption:
+		if err = quarantine(file, quarantinePath(path, offset), offset); err != nil {
+			return fmt.Errorf("quarantine of %s was failed: %w", path, err)
+		}
+
+		fmt.Printf("warning: broken record at offset %d of %s, %d bytes are moved to %s\n",
+			offset, path, lost, quarantinePath(path, offset))
+	default:
+		return fmt.Errorf("%w: broken record at offset %d of %s: %w", ErrCorruptedLog, offset, path, err)
+	}
+
+	if err = file.Truncate(offset); err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
+
+// quarantine copies the file from offset to the end into the new file
+func quarantine(file *os.File, path string, offset int64) error {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, file)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,131 @@
+package transaction
+
+import (
+	"bytes"
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// encode returns log of events and offsets where every record ends
+func encode(t *testing.T, events []core.Event) ([]byte, []int) {
+	t.Helper()
+
+	buf := bytes.NewBuffer(nil)
+	if err := binaryEvent.WriteHeader(buf, binaryEvent.Header{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ends []int
+
+	for _, e := range events {
+		if err := binaryEvent.WriteTo(buf, e); err != nil {
+			t.Fatal(err)
+		}
+
+		ends = append(ends, buf.Len())
+	}
+
+	return buf.Bytes(), ends
+}
+
+// openLog opens the log which only segment is data
+func openLog(t *testing.T, path string, data []byte, policy CorruptionPolicy) (core.TransactionLogger, error) {
+	t.Helper()
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(segmentPath(path, 1), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4, Corruption: policy})
+	if err == nil {
+		t.Cleanup(func() { _ = tl.Shutdown(context.Background()) })
+	}
+
+	return tl, err
+}
+
+var recoveryEvents = []core.Event{
+	{ID: 1, Time: 1730000000000000000, Type: core.EventPut, Key: "first", Value: []byte("1")},
+	{ID: 2, Type: core.EventTxn, Batch: []core.Event{
+		{Type: core.EventPut, Key: "second", Value: []byte("2")},
+		{Type: core.EventDelete, Key: "first"},
+	}},
+	{ID: 3, Type: core.EventPutWithTTL, Key: "third", Value: []byte("3"), Deadline: 1730000000000000000},
+}
+
+func TestCrashAtEveryOffset(t *testing.T) {
+	data, ends := encode(t, recoveryEvents)
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	for cut := 0; cut <= len(data); cut++ {
+		tails := [][]byte{nil}
+
+		//torn tail could be cut or filled by zeros when file was extended before data was written,
+		//header is synced when file is created, so it could be only cut
+		if cut >= binaryEvent.HeaderSize {
+			tails = append(tails, make([]byte, len(data)-cut))
+		}
+
+		for _, tail := range tails {
+			tl, err := openLog(t, path, append(bytes.Clone(data[:cut]), tail...), FailOnCorruption)
+			if err != nil {
+				t.Fatalf("cut at %d: %v", cut, err)
+			}
+
+			complete, size := 0, binaryEvent.HeaderSize
+			for complete < len(ends) && ends[complete] <= cut {
+				size = ends[complete]
+				complete++
+			}
+
+			if got := readAllEvents(t, tl); !reflect.DeepEqual(got, recoveryEvents[:complete]) && complete+len(got) != 0 {
+				t.Fatalf("cut at %d: got %v, want %v", cut, got, recoveryEvents[:complete])
+			}
+
+			if got := tl.(*FileLogger).LogSize(); got != int64(size-binaryEvent.HeaderSize) {
+				t.Fatalf("cut at %d: got size %d, want %d", cut, got, size)
+			}
+		}
+	}
+}
+
+func TestCorruptionInTheMiddle(t *testing.T) {
+	data, ends := encode(t, recoveryEvents)
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	//every byte of the second record is broken, the third one is still after it
+	for i := ends[0]; i < ends[1]; i++ {
+		broken := bytes.Clone(data)
+		broken[i] ^= 0x10
+
+		if _, err := openLog(t, path, broken, FailOnCorruption); !errors.Is(err, ErrCorruptedLog) {
+			t.Fatalf("byte %d: got error %v, want %v", i, err, ErrCorruptedLog)
+		}
+
+		tl, err := openLog(t, path, broken, QuarantineCorruption)
+		if err != nil {
+			t.Fatalf("byte %d: %v", i, err)
+		}
+
+		if got := readAllEvents(t, tl); !reflect.DeepEqual(got, recoveryEvents[:1]) {
+			t.Fatalf("byte %d: got %v, want %v", i, got, recoveryEvents[:1])
+		}
+
+		quarantined, err := os.ReadFile(quarantinePath(segmentPath(path, 1), int64(ends[0])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(quarantined, broken[ends[0]:]) {
+			t.Fatalf("byte %d: quarantined file is not the rest of the log", i)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package transaction
+
+import (
+	"bufio"
+	"cache/core"
+	"cache/transaction/binaryEvent"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Log is a directory, "snapshot" keeps the state at some event and segments
+// "00000000000000000042.log" keep events starting from ID 42, events are
+// appended to the last segment. Segment is covered by the snapshot if the
+// next segment starts right after the snapshot or before it
+const segmentExt = ".log"
+
+func snapshotPath(dir string) string {
+	return filepath.Join(dir, "snapshot")
+}
+
+func segmentPath(dir string, first uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", first, segmentExt))
+}
+
+type segment struct {
+	path  string
+	first uint64
+}
+
+// listSegments returns segments of the log in order of their events
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []segment
+
+	for _, entry := range entries {
+		name, found := strings.CutSuffix(entry.Name(), segmentExt)
+		if !found || entry.IsDir() {
+			continue
+		}
+
+		//quarantined, migrated and temporary files are not segments
+		first, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		segments = append(segments, segment{filepath.Join(dir, entry.Name()), first})
+	}
+
+	slices.SortFunc(segments, func(a, b segment) int {
+		return cmp.Compare(a.first, b.first)
+	})
+
+	return segments, nil
+}
+
+// readSnapshotID returns ID of the last event included in the snapshot, zero if there is no snapshot
+func readSnapshotID(dir string, keys *binaryEvent.Keyring) (uint64, error) {
+	file, err := os.Open(snapshotPath(dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+
+	header, err := ReadHeader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	codec, err := NewCodec(header, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	e, err := codec.Read(r)
+	if err != nil {
+		return 0, err
+	}
+
+	return e.ID, nil
+}
+
+// sizeSinceSnapshot returns size of segments those are not covered by the snapshot
+func sizeSinceSnapshot(dir string, keys *binaryEvent.Keyring) (int64, error) {
+	snapshotID, err := readSnapshotID(dir, keys)
+	if err != nil {
+		return 0, fmt.Errorf("read snapshot was failed: %w", err)
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+
+	for i, s := range segments {
+		if i+1 < len(segments) && segments[i+1].first <= snapshotID+1 {
+			continue
+		}
+
+		info, err := os.Stat(s.path)
+		if err != nil {
+			return 0, err
+		}
+
+		header, err := readFileHeader(s.path)
+		if err != nil && !errors.Is(err, binaryEvent.ErrEmptyFile) {
+			return 0, err
+		}
+
+		size += max(info.Size()-headerSize(header), 0)
+	}
+
+	return size, nil
+}
+
+// readFileHeader reads header of the file without its records
+func readFileHeader(path string) (binaryEvent.Header, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return binaryEvent.Header{}, err
+	}
+	defer file.Close()
+
+	return ReadHeader(bufio.NewReader(file))
+}
+
+// removeCovered removes segments those events are all in the snapshot,
+// the newest retain of them are kept
+func removeCovered(dir string, snapshotID uint64, retain int) error {
+	segments, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	covered := 0
+	for covered+1 < len(segments) && segments[covered+1].first <= snapshotID+1 {
+		covered++
+	}
+
+	for _, s := range segments[:max(covered-retain, 0)] {
+		if err = os.Remove(s.path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// countWriter counts written bytes, so size of the log is known without stat
+type countWriter struct {
+	io.Writer
+	size    *atomic.Int64
+	segment *int64
+}
+
+func (w countWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.size.Add(int64(n))
+	*w.segment += int64(n)
+
+	return n, err
+}
+
+// write is called by the writer goroutine, the log is rolled over
+// before the event if the active segment is full or old
+func (tl *FileLogger) write(e core.Event) error {
+	bySize := tl.opts.SegmentSize > 0 && tl.segmentSize >= tl.opts.SegmentSize
+	byAge := tl.opts.SegmentAge > 0 && time.Since(tl.segmentStart) >= tl.opts.SegmentAge
+
+	if bySize || byAge {
+		if err := tl.roll(e.ID); err != nil {
+			return fmt.Errorf("roll log over was failed: %w", err)
+		}
+	}
+
+	segmentSize, size := tl.segmentSize, tl.size.Load()
+
+	err := tl.codec.Write(countWriter{tl.file, &tl.size, &tl.segmentSize}, e)
+
+	//key of encrypted segment is rotated with the segment, nothing is written before it
+	if errors.Is(err, binaryEvent.ErrKeyExhausted) {
+		if err = tl.roll(e.ID); err != nil {
+			return fmt.Errorf("roll log over was failed: %w", err)
+		}
+
+		err = tl.codec.Write(countWriter{tl.file, &tl.size, &tl.segmentSize}, e)
+	}
+	if err == nil {
+		return nil
+	}
+
+	//part of the record written before the failure would break the log for
+	//records written after it, when disk has space again
+	if truncErr := tl.file.Truncate(tl.headerSize + segmentSize); truncErr != nil {
+		return errors.Join(err, fmt.Errorf("truncate failed record was failed: %w", truncErr))
+	}
+
+	tl.segmentSize = segmentSize
+	tl.size.Store(size)
+
+	return err
+}
+
+// roll starts the new segment with the first event, empty segment is not rolled
+// over and the active one is kept if the new one could not be created
+func (tl *FileLogger) roll(first uint64) error {
+	if tl.segmentSize <= 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(segmentPath(tl.path, first), os.O_RDWR|os.O_APPEND|os.O_CREATE, 0755)
+	if err != nil {
+		return err
+	}
+
+	codec, header, err := ensureHeader(file, tl.opts.header(), tl.opts.Keys)
+	if err == nil && tl.opts.Durability != NoSync {
+		//the new segment should not be lost with events synced to it
+		if err = syncDir(tl.path); err == nil {
+			err = tl.sync()
+		}
+	}
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	previous := tl.file
+	tl.file, tl.codec, tl.headerSize = file, codec, header
+	tl.segmentSize, tl.segmentStart = 0, time.Now()
+
+	return previous.Close()
+}
+
+// upgradeLayout moves the log of one file "logs.bin" with its snapshot "logs.bin.snapshot"
+// and rotated logs "logs.bin.42" with last ID in the name into the directory, it could
+// be interrupted and called again
+func upgradeLayout(path string) error {
+	info, err := os.Stat(path)
+	if err == nil && info.IsDir() {
+		return nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	tmp := path + ".upgrading"
+	if err = os.MkdirAll(tmp, 0755); err != nil {
+		return err
+	}
+
+	rotated, err := rotatedLogs(path)
+	if err != nil {
+		return err
+	}
+
+	first := uint64(1)
+
+	//segments moved before interruption are already there
+	if moved, err := listSegments(tmp); err != nil {
+		return err
+	} else if len(moved) != 0 {
+		first = moved[len(moved)-1].first + 1
+	}
+
+	for _, r := range rotated {
+		if err = os.Rename(r.path, segmentPath(tmp, first)); err != nil {
+			return err
+		}
+
+		first = r.first + 1
+	}
+
+	moves := [][2]string{
+		{path + ".snapshot", snapshotPath(tmp)},
+		{path, segmentPath(tmp, first)},
+	}
+
+	for _, move := range moves {
+		if err = os.Rename(move[0], move[1]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// rotatedLogs returns rotated logs of the layout of one file in their order,
+// first of the returned segments is the last ID of the rotated log
+func rotatedLogs(path string) ([]segment, error) {
+	dir, prefix := filepath.Dir(path), filepath.Base(path)+"."
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var rotated []segment
+
+	for _, entry := range entries {
+		suffix, found := strings.CutPrefix(entry.Name(), prefix)
+		if !found || entry.IsDir() {
+			continue
+		}
+
+		lastID, err := strconv.ParseUint(suffix, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		rotated = append(rotated, segment{filepath.Join(dir, entry.Name()), lastID})
+	}
+
+	slices.SortFunc(rotated, func(a, b segment) int {
+		return cmp.Compare(a.first, b.first)
+	})
+
+	return rotated, nil
+}
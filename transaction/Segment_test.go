@@ -0,0 +1,148 @@
+package transaction
+
+import (
+	"cache/core"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestRollOverAndRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	//every event goes to its own segment
+	tl, err := NewLogger(path, Options{Bandwidth: 4, SegmentSize: 1, RetainSegments: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Shutdown(context.Background())
+
+	tl.Start()
+
+	for id := uint64(1); id <= 5; id++ {
+		tl.WriteEvent(put(id))
+	}
+	tl.(*FileLogger).Wait()
+
+	var want []string
+	for id := uint64(1); id <= 5; id++ {
+		want = append(want, filepath.Base(segmentPath(path, id)))
+	}
+	if names := dirNames(t, path); !slices.Equal(names, want) {
+		t.Fatalf("got files %v, want %v", names, want)
+	}
+
+	state := []core.Event{{ID: 5, Type: core.EventSnapshot}, put(5)}
+	if err = <-tl.(core.Compactor).Compact(state); err != nil {
+		t.Fatal(err)
+	}
+
+	//the newest covered segment is retained
+	want = []string{filepath.Base(segmentPath(path, 5)), filepath.Base(segmentPath(path, 6)), "snapshot"}
+	if names := dirNames(t, path); !slices.Equal(names, want) {
+		t.Fatalf("got files %v, want %v", names, want)
+	}
+
+	tl.WriteEvent(put(6))
+	tl.(*FileLogger).Wait()
+
+	if got := readAllEvents(t, tl); !reflect.DeepEqual(got, append(state, put(6))) {
+		t.Fatalf("got %v, want %v", got, append(state, put(6)))
+	}
+
+	size := tl.(core.Compactor).LogSize()
+
+	reopened, err := NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Shutdown(context.Background())
+
+	if got := reopened.(core.Compactor).LogSize(); got != size {
+		t.Fatalf("got size %d after reopen, want %d", got, size)
+	}
+}
+
+func TestUpgradeLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	//log of one file with rotated logs named by their last IDs
+	writeEvents(t, path+".snapshot", core.Event{ID: 3, Type: core.EventSnapshot})
+	writeEvents(t, path+".3", put(1), put(2), put(3))
+	writeEvents(t, path+".10", put(4), put(10))
+	writeEvents(t, path, put(11))
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []core.Event{{ID: 3, Type: core.EventSnapshot}, put(4), put(10), put(11)}
+	if got := readAllEvents(t, tl); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	names := dirNames(t, path)
+	if want := []string{
+		filepath.Base(segmentPath(path, 1)),
+		filepath.Base(segmentPath(path, 4)),
+		filepath.Base(segmentPath(path, 11)),
+		"snapshot",
+	}; !slices.Equal(names, want) {
+		t.Fatalf("got files %v, want %v", names, want)
+	}
+
+	if _, err = os.Stat(path + ".upgrading"); !os.IsNotExist(err) {
+		t.Fatalf("got %v, want no temporary directory", err)
+	}
+}
+
+func TestReadDuringCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.bin")
+
+	tl, err := NewLogger(path, Options{Bandwidth: 4, SegmentSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Shutdown(context.Background())
+
+	tl.Start()
+
+	compactor := tl.(core.Compactor)
+	first := []core.Event{{ID: 2, Type: core.EventSnapshot}, put(1), put(2)}
+
+	for id := uint64(1); id <= 2; id++ {
+		tl.WriteEvent(put(id))
+	}
+	if err = <-compactor.Compact(first); err != nil {
+		t.Fatal(err)
+	}
+	for id := uint64(3); id <= 5; id++ {
+		tl.WriteEvent(put(id))
+	}
+	tl.(*FileLogger).Wait()
+
+	//reader is in the middle of the first snapshot while the second one
+	//replaces it and removes segments of events 3-5
+	events, errs := tl.ReadEvents()
+	got := []core.Event{<-events}
+
+	if err = <-compactor.Compact([]core.Event{{ID: 5, Type: core.EventSnapshot}, put(5)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for e := range events {
+		got = append(got, e)
+	}
+	if err = <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	want := append(first, put(3), put(4), put(5))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package transaction
+
+import (
+	"cache/core"
+	"fmt"
+	"os"
+)
+
+// compact is called by the writer goroutine, so the log is rolled over exactly after
+// the last event included in the state, snapshot is saved without blocking writes
+func (tl *FileLogger) compact(r record) {
+	lastID := r.state[0].ID
+
+	if err := tl.roll(lastID + 1); err != nil {
+		r.done <- fmt.Errorf("roll log over was failed: %w", err)
+		tl.wg.Done()
+		return
+	}
+
+	tl.size.Store(0)
+
+	go func() {
+		defer tl.wg.Done()
+		r.done <- tl.saveSnapshot(lastID, r.state)
+	}()
+}
+
+// saveSnapshot writes the state to temporary file and replaces the previous snapshot
+// by it, segments covered by the snapshot are removed after that by retention policy
+func (tl *FileLogger) saveSnapshot(lastID uint64, state []core.Event) error {
+	tmp := snapshotPath(tl.path) + ".tmp"
+
+	if err := writeFile(tmp, state, tl.opts.header(), tl.opts.Keys); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	tl.generation.Lock()
+	defer tl.generation.Unlock()
+
+	if err := os.Rename(tmp, snapshotPath(tl.path)); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return removeCovered(tl.path, lastID, tl.opts.RetainSegments)
+}
@@ -2,22 +2,27 @@ package binaryEvent
 
 import (
 	"bufio"
+	"bytes"
 	"cache/core"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
-	"slices"
+	"math"
 )
 
-var ErrLongField = errors.New("field is too long")
 var ErrEmptyFile = errors.New("file is empty")
+var ErrTruncated = errors.New("record is truncated")
+var ErrChecksum = errors.New("checksum of record does not match")
 
-func writeNum(buf *bufio.Writer, n uint64) error {
-	binaryLen := make([]byte, 8)
-	binary.LittleEndian.PutUint64(binaryLen, n)
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
 
-	if _, err := buf.Write(binaryLen[0 : slices.Index(binaryLen, 0)+1]); err != nil {
+// writeNum writes n as uvarint, it is the number encoding of files with header,
+// so IDs survive restore exactly. Numbers of headerless LegacyVersion files
+// are read only by readLegacyNum
+func writeNum(buf *bufio.Writer, n uint64) error {
+	if _, err := buf.Write(binary.AppendUvarint(nil, n)); err != nil {
 		return err
 	}
 
@@ -25,28 +30,15 @@ func writeNum(buf *bufio.Writer, n uint64) error {
 }
 
 func readNum(buf *bufio.Reader) (uint64, error) {
-	var n uint64
-
-	for i := 0; i < 8; i++ {
-		b, err := buf.ReadByte()
-		if err != nil {
-			return 0, err
-		}
-		if b == 0 {
-			break
-		}
-
-		n += uint64(b)
+	n, err := binary.ReadUvarint(buf)
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
 	}
 
-	return n, nil
+	return n, err
 }
 
 func writeString(buf *bufio.Writer, str string) error {
-	if buf.Available() <= len(str) {
-		return ErrLongField
-	}
-
 	if err := writeNum(buf, uint64(len(str))); err != nil {
 		return err
 	}
@@ -58,28 +50,73 @@ func writeString(buf *bufio.Writer, str string) error {
 	return nil
 }
 
-func readString(buf *bufio.Reader) (string, error) {
+func writeBytes(buf *bufio.Writer, b []byte) error {
+	if err := writeNum(buf, uint64(len(b))); err != nil {
+		return err
+	}
+
+	if _, err := buf.Write(b); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// preallocLimit is the biggest length those is allocated at once,
+// length could be broken, so bigger fields grow while they are read
+const preallocLimit = 64 << 10
+
+func readBytes(buf *bufio.Reader) ([]byte, error) {
 	length, err := readNum(buf)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	return readN(buf, length)
+}
+
+func readN(buf *bufio.Reader, length uint64) ([]byte, error) {
+	if length == 0 {
+		return nil, nil
 	}
 
-	str := make([]byte, length)
-	if _, err := io.ReadFull(buf, str); err != nil {
-		return "", err
+	if length <= preallocLimit {
+		b := make([]byte, length)
+		if _, err := io.ReadFull(buf, b); err != nil {
+			return nil, err
+		}
+
+		return b, nil
 	}
 
-	return string(str), nil
-}
+	if length > math.MaxInt64 {
+		return nil, io.ErrUnexpectedEOF
+	}
 
-func WriteTo(w io.Writer, e core.Event) error {
-	tmp := "write %s of event was failed: %w"
-	buf := bufio.NewWriter(w)
+	b := bytes.NewBuffer(make([]byte, 0, preallocLimit))
+	if _, err := io.CopyN(b, buf, int64(length)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
 
-	if err := writeNum(buf, e.ID); err != nil {
-		return fmt.Errorf(tmp, "ID", err)
+		return nil, err
 	}
 
+	return b.Bytes(), nil
+}
+
+func readString(buf *bufio.Reader) (string, error) {
+	b, err := readBytes(buf)
+	return string(b), err
+}
+
+var ErrNestedTxn = errors.New("transaction could not contain another transaction")
+
+// writeBody writes everything except ID, events of transaction are written
+// the same way one after another
+func writeBody(buf *bufio.Writer, e core.Event) error {
+	tmp := "write %s of event was failed: %w"
+
 	if err := buf.WriteByte(e.Type); err != nil {
 		return fmt.Errorf(tmp, "type", err)
 	}
@@ -88,35 +125,291 @@ func WriteTo(w io.Writer, e core.Event) error {
 		return fmt.Errorf(tmp, "key", err)
 	}
 
-	if err := writeString(buf, e.Value); err != nil {
+	if core.HasField(e.Type) {
+		if err := writeString(buf, e.Field); err != nil {
+			return fmt.Errorf(tmp, "field", err)
+		}
+	}
+
+	if err := writeBytes(buf, e.Value); err != nil {
 		return fmt.Errorf(tmp, "value", err)
 	}
 
-	return buf.Flush()
+	if core.HasContentType(e.Type) {
+		if err := writeString(buf, e.ContentType); err != nil {
+			return fmt.Errorf(tmp, "content type", err)
+		}
+	}
+
+	if core.HasDeadline(e.Type) {
+		if err := binary.Write(buf, binary.LittleEndian, e.Deadline); err != nil {
+			return fmt.Errorf(tmp, "deadline", err)
+		}
+	}
+
+	if e.Type == core.EventTxn {
+		if err := writeNum(buf, uint64(len(e.Batch))); err != nil {
+			return fmt.Errorf(tmp, "batch size", err)
+		}
+
+		for _, op := range e.Batch {
+			if op.Type == core.EventTxn {
+				return ErrNestedTxn
+			}
+
+			if err := writeBody(buf, op); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
-func Read(r io.Reader) (e core.Event, err error) {
+func readBody(buf *bufio.Reader, e *core.Event) (err error) {
 	tmp := "read %s of event was failed: %w"
+
+	if e.Type, err = buf.ReadByte(); err != nil {
+		return fmt.Errorf(tmp, "type", err)
+	}
+
+	if e.Key, err = readString(buf); err != nil {
+		return fmt.Errorf(tmp, "key", err)
+	}
+
+	if core.HasField(e.Type) {
+		if e.Field, err = readString(buf); err != nil {
+			return fmt.Errorf(tmp, "field", err)
+		}
+	}
+
+	if e.Value, err = readBytes(buf); err != nil {
+		return fmt.Errorf(tmp, "value", err)
+	}
+
+	if core.HasContentType(e.Type) {
+		if e.ContentType, err = readString(buf); err != nil {
+			return fmt.Errorf(tmp, "content type", err)
+		}
+	}
+
+	if core.HasDeadline(e.Type) {
+		if err = binary.Read(buf, binary.LittleEndian, &e.Deadline); err != nil {
+			return fmt.Errorf(tmp, "deadline", err)
+		}
+	}
+
+	if e.Type == core.EventTxn {
+		size, err := readNum(buf)
+		if err != nil {
+			return fmt.Errorf(tmp, "batch size", err)
+		}
+
+		//size could be broken, so it is not trusted for preallocation
+		if size > 0 {
+			e.Batch = make([]core.Event, 0, min(size, 1024))
+		}
+
+		for i := uint64(0); i < size; i++ {
+			var op core.Event
+			if err = readBody(buf, &op); err != nil {
+				return err
+			}
+			if op.Type == core.EventTxn {
+				return ErrNestedTxn
+			}
+
+			e.Batch = append(e.Batch, op)
+		}
+	}
+
+	return nil
+}
+
+// WriteTo writes whole event or nothing, record is encoded in memory first,
+// because event could be bigger than buffer of writer. Record is framed as
+// length, checksum of length, payload and checksum of payload, length has its
+// own checksum, so broken length is not mistaken for record cut by crash
+func WriteTo(w io.Writer, e core.Event) error {
+	return Encoding{}.Write(w, e)
+}
+
+// Write writes record of the event encoded by Marshal
+func (enc Encoding) Write(w io.Writer, e core.Event) error {
+	payload, err := Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return enc.WriteRecord(w, payload)
+}
+
+// Marshal encodes the event as payload of the record
+func Marshal(e core.Event) ([]byte, error) {
+	payload := bytes.NewBuffer(nil)
+	buf := bufio.NewWriter(payload)
+
+	if err := writeNum(buf, e.ID); err != nil {
+		return nil, fmt.Errorf("write ID of event was failed: %w", err)
+	}
+
+	//events of transaction share its time, so it is written only once
+	if _, err := buf.Write(binary.AppendVarint(nil, e.Time)); err != nil {
+		return nil, fmt.Errorf("write time of event was failed: %w", err)
+	}
+
+	if err := writeBody(buf, e); err != nil {
+		return nil, err
+	}
+
+	if err := buf.Flush(); err != nil {
+		return nil, err
+	}
+
+	return payload.Bytes(), nil
+}
+
+// WriteRecord writes record which payload is compressed and then encrypted, checksums
+// are of the written payload, so broken record is found before it is decrypted.
+// ErrKeyExhausted means that the record should be written to the new file
+func (enc Encoding) WriteRecord(w io.Writer, payload []byte) error {
+	compressed, err := compress(enc.Compression, payload)
+	if err != nil {
+		return fmt.Errorf("compress event was failed: %w", err)
+	}
+
+	if compressed, err = encrypt(enc.aead, compressed, enc.additionalData()); err != nil {
+		return fmt.Errorf("encrypt event was failed: %w", err)
+	}
+
+	length := binary.AppendUvarint(nil, uint64(len(compressed)))
+
+	record := make([]byte, 0, len(length)+len(compressed)+8)
+	record = append(record, length...)
+	record = binary.LittleEndian.AppendUint32(record, crc32.Checksum(length, castagnoli))
+	record = append(record, compressed...)
+	record = binary.LittleEndian.AppendUint32(record, crc32.Checksum(compressed, castagnoli))
+
+	if enc.aead != nil && *enc.offset+int64(len(record)) > MaxEncryptedSize {
+		return ErrKeyExhausted
+	}
+
+	if _, err = w.Write(record); err != nil {
+		return err
+	}
+
+	enc.advance(len(record))
+
+	return nil
+}
+
+// additionalData of the next record, nil if records are not encrypted
+func (enc Encoding) additionalData() []byte {
+	if enc.aead == nil {
+		return nil
+	}
+
+	return additionalData(enc.header, *enc.offset)
+}
+
+// advance moves offset of encrypted file after the record
+func (enc Encoding) advance(size int) {
+	if enc.offset != nil {
+		*enc.offset += int64(size)
+	}
+}
+
+// readChecksum reads checksum and compares it with checksum of data
+func readChecksum(buf *bufio.Reader, data []byte) error {
+	var sum uint32
+	if err := binary.Read(buf, binary.LittleEndian, &sum); err != nil {
+		return ErrTruncated
+	}
+
+	if sum != crc32.Checksum(data, castagnoli) {
+		return ErrChecksum
+	}
+
+	return nil
+}
+
+// Read reads one record, ErrTruncated means that reader ended inside the record
+// and ErrChecksum means that record is broken
+func Read(r io.Reader) (core.Event, error) {
+	return Encoding{}.Read(r)
+}
+
+// Read reads one record written by Write
+func (enc Encoding) Read(r io.Reader) (core.Event, error) {
+	payload, err := enc.ReadRecord(r)
+	if err != nil {
+		return core.Event{}, err
+	}
+
+	return Unmarshal(payload)
+}
+
+// ReadRecord reads payload of one record written by WriteRecord, ErrDecrypt means that
+// record has right checksums, but it is encrypted by another key or it was changed on purpose
+func (enc Encoding) ReadRecord(r io.Reader) ([]byte, error) {
 	buf := bufio.NewReader(r)
 
-	if _, err = buf.Peek(1); err != nil {
-		return e, ErrEmptyFile
+	if _, err := buf.Peek(1); err != nil {
+		return nil, ErrEmptyFile
 	}
 
-	if e.ID, err = readNum(buf); err != nil {
-		return e, fmt.Errorf(tmp, "id", err)
+	length, err := binary.ReadUvarint(buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("read length of record was failed: %w", ErrTruncated)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read length of record was failed: %w", ErrChecksum)
 	}
 
-	if e.Type, err = buf.ReadByte(); err != nil {
-		return e, fmt.Errorf(tmp, "type", err)
+	if err = readChecksum(buf, binary.AppendUvarint(nil, length)); err != nil {
+		return nil, fmt.Errorf("read length of record was failed: %w", err)
 	}
 
-	if e.Key, err = readString(buf); err != nil {
-		return e, fmt.Errorf(tmp, "key", err)
+	payload, err := readN(buf, length)
+	if err != nil {
+		return nil, fmt.Errorf("read record was failed: %w", ErrTruncated)
+	}
+
+	if err = readChecksum(buf, payload); err != nil {
+		return nil, fmt.Errorf("read record was failed: %w", err)
+	}
+
+	if payload, err = decrypt(enc.aead, payload, enc.additionalData()); err != nil {
+		return nil, fmt.Errorf("read record was failed: %w", err)
+	}
+
+	enc.advance(len(binary.AppendUvarint(nil, length)) + 4 + int(length) + 4)
+
+	if payload, err = decompress(enc.Compression, payload); err != nil {
+		return nil, fmt.Errorf("decompress record was failed: %w", err)
+	}
+
+	return payload, nil
+}
+
+// Unmarshal decodes the event from payload of the record encoded by Marshal
+func Unmarshal(payload []byte) (e core.Event, err error) {
+	body := bufio.NewReader(bytes.NewReader(payload))
+
+	if e.ID, err = readNum(body); err != nil {
+		return e, fmt.Errorf("read id of event was failed: %w", err)
+	}
+
+	if e.Time, err = binary.ReadVarint(body); err != nil {
+		return e, fmt.Errorf("read time of event was failed: %w", err)
+	}
+
+	if err = readBody(body, &e); err != nil {
+		return e, err
 	}
 
-	if e.Value, err = readString(buf); err != nil {
-		return e, fmt.Errorf(tmp, "value", err)
+	if _, err = body.Peek(1); err == nil {
+		return e, errors.New("record has bytes after event")
 	}
 
 	return e, nil
@@ -1,6 +1,7 @@
 package binaryEvent
 
 import (
+	"bufio"
 	"bytes"
 	"cache/core"
 	"errors"
@@ -24,7 +25,7 @@ var cases = []Case{
 			ID:    14,
 			Type:  core.EventPut,
 			Key:   "abc",
-			Value: "cba",
+			Value: []byte("cba"),
 		},
 	},
 	{
@@ -33,7 +34,7 @@ var cases = []Case{
 			ID:    14,
 			Type:  core.EventPut,
 			Key:   "abc asdasd sadsad",
-			Value: "cba asdsad asdasd",
+			Value: []byte("cba asdsad asdasd"),
 		},
 	},
 	{
@@ -42,16 +43,126 @@ var cases = []Case{
 			ID:    14,
 			Type:  core.EventPut,
 			Key:   "ASPOJSDA msapjodфыхв)(ЦЙУШО)шоыфывфтщ ыфвщтывфтфыъ-930032двй0-3939гцов-0392213ВЛТОВЫтштщNSOSDNu jsdnds==-2133221",
-			Value: "ASPOIJ{SDAJВЫОЫВФльokDJJ*(0pl/\\	p9838j3838hnnosaISA09102312301mSuыфшвыфвыфзывыфтвфгышвфывы09=ёё1221ё",
+			Value: []byte("ASPOIJ{SDAJВЫОЫВФльokDJJ*(0pl/\\	p9838j3838hnnosaISA09102312301mSuыфшвыфвыфзывыфтвфгышвфывы09=ёё1221ё"),
 		},
 	},
 	{
-		name: "put too long",
+		name: "put longer than buffer",
 		event: core.Event{
 			ID:    14,
 			Type:  core.EventPut,
 			Key:   strings.Repeat("a", math.MaxInt32/300),
-			Value: strings.Repeat("b", math.MaxInt32/300),
+			Value: []byte(strings.Repeat("b", math.MaxInt32/300)),
+		},
+	},
+	{
+		name: "put with id those bytes contain zeros",
+		event: core.Event{
+			ID:    256,
+			Type:  core.EventPut,
+			Key:   "abc",
+			Value: []byte("cba"),
+		},
+	},
+	{
+		name: "put with max id",
+		event: core.Event{
+			ID:    math.MaxUint64,
+			Type:  core.EventPut,
+			Key:   "abc",
+			Value: []byte("cba"),
+		},
+	},
+	{
+		name: "put with ttl",
+		event: core.Event{
+			ID:       14,
+			Type:     core.EventPutWithTTL,
+			Key:      "abc",
+			Value:    []byte("cba"),
+			Deadline: 1730000000000000000,
+		},
+	},
+	{
+		name: "put increment result",
+		event: core.Event{
+			ID:       14,
+			Type:     core.EventIncrement,
+			Key:      "counter",
+			Value:    []byte("-15"),
+			Deadline: 1730000000000000000,
+		},
+	},
+	{
+		name: "put field of hash",
+		event: core.Event{
+			ID:    14,
+			Type:  core.EventHashSet,
+			Key:   "hash",
+			Field: "field",
+			Value: []byte("value"),
+		},
+	},
+	{
+		name: "put to the list",
+		event: core.Event{
+			ID:    14,
+			Type:  core.EventListPushLeft,
+			Key:   "list",
+			Value: []byte("item"),
+		},
+	},
+	{
+		name: "put binary value with content type",
+		event: core.Event{
+			ID:          14,
+			Type:        core.EventPut,
+			Key:         "thumbnail",
+			Value:       []byte{0x89, 'P', 'N', 'G', 0, 0xff, '\n', 0x80},
+			ContentType: "image/png",
+		},
+	},
+	{
+		name: "put member of sorted set",
+		event: core.Event{
+			ID:    14,
+			Type:  core.EventZSetAdd,
+			Key:   "zset",
+			Field: "member",
+			Value: []byte("-1.5e-07"),
+		},
+	},
+	{
+		name: "put in transaction",
+		event: core.Event{
+			ID:   14,
+			Time: 1730000000000000000,
+			Type: core.EventTxn,
+			Batch: []core.Event{
+				{Type: core.EventPut, Key: "abc", Value: []byte("cba")},
+				{Type: core.EventPutWithTTL, Key: "ttl", Value: []byte("value"), Deadline: 1730000000000000000},
+				{Type: core.EventDelete, Key: "deleted"},
+			},
+		},
+	},
+	{
+		name: "put with time",
+		event: core.Event{
+			ID:    14,
+			Time:  1730000000123456789,
+			Type:  core.EventPut,
+			Key:   "abc",
+			Value: []byte("cba"),
+		},
+	},
+	{
+		name: "put with time before epoch",
+		event: core.Event{
+			ID:    14,
+			Time:  -1,
+			Type:  core.EventPut,
+			Key:   "abc",
+			Value: []byte("cba"),
 		},
 	},
 	{
@@ -60,7 +171,7 @@ var cases = []Case{
 			ID:    14,
 			Type:  core.EventPut,
 			Key:   "",
-			Value: "avc",
+			Value: []byte("avc"),
 		},
 	},
 	{
@@ -69,7 +180,7 @@ var cases = []Case{
 			ID:    14,
 			Type:  core.EventPut,
 			Key:   "avc",
-			Value: "",
+			Value: []byte{},
 		},
 	},
 	{
@@ -78,7 +189,7 @@ var cases = []Case{
 			ID:    14,
 			Type:  core.EventPut,
 			Key:   "avc",
-			Value: "",
+			Value: []byte{},
 		},
 	},
 }
@@ -90,9 +201,10 @@ func init() {
 			name: strings.Replace(c.name, "put", "delete", 1),
 			event: core.Event{
 				ID:    c.event.ID,
+				Time:  c.event.Time,
 				Type:  core.EventDelete,
 				Key:   c.event.Key,
-				Value: "",
+				Value: nil,
 			},
 		}
 
@@ -100,6 +212,97 @@ func init() {
 	}
 }
 
+func TestNestedTransaction(t *testing.T) {
+	event := core.Event{
+		Type:  core.EventTxn,
+		Batch: []core.Event{{Type: core.EventTxn}},
+	}
+
+	mockFile := bytes.NewBuffer(nil)
+	if err := WriteTo(mockFile, event); !errors.Is(err, ErrNestedTxn) {
+		t.Fatalf("got error %v, want %v", err, ErrNestedTxn)
+	}
+	if mockFile.Len() != 0 {
+		t.Fatal("unempty file buffer after writing error")
+	}
+}
+
+func TestReadSequence(t *testing.T) {
+	mockFile := bytes.NewBuffer(nil)
+
+	for i := range cases {
+		if err := WriteTo(mockFile, cases[i].event); err != nil {
+			t.Fatalf("case %q: %v", cases[i].name, err)
+		}
+	}
+
+	//one reader for the whole file like in FileLogger
+	r := bufio.NewReader(mockFile)
+
+	for i := range cases {
+		got, err := Read(r)
+		if err != nil {
+			t.Fatalf("case %q: %v", cases[i].name, err)
+		}
+		if !equalEvents(got, cases[i].event) {
+			t.Fatalf("case %q: got %v, want %v", cases[i].name, got, cases[i].event)
+		}
+	}
+
+	if _, err := Read(r); !errors.Is(err, ErrEmptyFile) {
+		t.Fatalf("got error %v after last event, want %v", err, ErrEmptyFile)
+	}
+}
+
+func TestTruncatedRecord(t *testing.T) {
+	record := bytes.NewBuffer(nil)
+	if err := WriteTo(record, cases[0].event); err != nil {
+		t.Fatal(err)
+	}
+
+	for cut := 1; cut < record.Len(); cut++ {
+		if _, err := Read(bytes.NewReader(record.Bytes()[:cut])); !errors.Is(err, ErrTruncated) {
+			t.Fatalf("cut at %d: got error %v, want %v", cut, err, ErrTruncated)
+		}
+	}
+}
+
+func TestBrokenRecord(t *testing.T) {
+	record := bytes.NewBuffer(nil)
+	if err := WriteTo(record, cases[0].event); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < record.Len(); i++ {
+		broken := bytes.Clone(record.Bytes())
+		broken[i] ^= 0x10
+
+		if _, err := Read(bytes.NewReader(broken)); !errors.Is(err, ErrChecksum) {
+			t.Fatalf("byte %d: got error %v, want %v", i, err, ErrChecksum)
+		}
+	}
+}
+
+// equalEvents does not distinguish nil and empty values, they are written the same way
+func equalEvents(a core.Event, b core.Event) bool {
+	if len(a.Value) == 0 && len(b.Value) == 0 {
+		a.Value, b.Value = nil, nil
+	}
+	if len(a.Batch) != len(b.Batch) {
+		return false
+	}
+
+	for i := range a.Batch {
+		if !equalEvents(a.Batch[i], b.Batch[i]) {
+			return false
+		}
+	}
+
+	a.Batch, b.Batch = nil, nil
+
+	return reflect.DeepEqual(a, b)
+}
+
 func summarizeString(str string, limit int) string {
 	return summarizeSlice([]rune(str), limit)
 }
@@ -125,14 +328,14 @@ func writeAndRead(t *testing.T, c Case) {
 		ID:    c.event.ID,
 		Type:  c.event.Type,
 		Key:   summarizeString(c.event.Key, 20),
-		Value: summarizeString(c.event.Value, 20),
+		Value: []byte(summarizeSlice(c.event.Value, 20)),
 	}
 
 	gotEventForPrint := core.Event{
 		ID:    gotEvent.ID,
 		Type:  gotEvent.Type,
 		Key:   summarizeString(gotEvent.Key, 20),
-		Value: summarizeString(gotEvent.Value, 20),
+		Value: []byte(summarizeSlice(gotEvent.Value, 20)),
 	}
 
 	info := fmt.Sprintf(
@@ -142,7 +345,7 @@ func writeAndRead(t *testing.T, c Case) {
 		writeErr, gotEventForPrint, readErr,
 	)
 
-	if writeErr != nil && !errors.Is(writeErr, ErrLongField) {
+	if writeErr != nil {
 		t.Errorf("unexpected error while writing" + info)
 	}
 
@@ -157,20 +360,28 @@ func writeAndRead(t *testing.T, c Case) {
 	}
 
 	//if we write no error, wrote gotEvent and read gotEvent should be equal
-	if writeErr == nil && !reflect.DeepEqual(c.event, gotEvent) {
+	if writeErr == nil && !equalEvents(c.event, gotEvent) {
 		t.Errorf("expected gotEvent and got gotEvent are different" + info)
 	}
 }
 
 func FuzzWriteReadRestore(f *testing.F) {
 	for _, test := range cases {
-		f.Add(test.name, test.event.ID, test.event.Type, test.event.Key, test.event.Value)
+		f.Add(test.name, test.event.ID, test.event.Time, test.event.Type, test.event.Key, test.event.Value, test.event.ContentType, test.event.Deadline)
 	}
 
-	f.Fuzz(func(t *testing.T, name string, ID uint64, eventType byte, key string, value string) {
+	f.Fuzz(func(t *testing.T, name string, ID uint64, eventTime int64, eventType byte, key string, value []byte, contentType string, deadline int64) {
+		//deadline and content type are written only for events those carry them
+		if !core.HasDeadline(eventType) {
+			deadline = 0
+		}
+		if !core.HasContentType(eventType) {
+			contentType = ""
+		}
+
 		testCase := Case{
 			name:  name,
-			event: core.Event{ID: ID, Type: eventType, Key: key, Value: value},
+			event: core.Event{ID: ID, Time: eventTime, Type: eventType, Key: key, Value: value, ContentType: contentType, Deadline: deadline},
 		}
 
 		writeAndRead(t, testCase)
@@ -0,0 +1,83 @@
+package binaryEvent
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Compression of payloads of records, every record is compressed separately,
+// so torn record is still detected and truncated alone
+type Compression byte
+
+const (
+	NoCompression Compression = iota
+	Flate
+)
+
+var ErrUnsupportedCompression = errors.New("unsupported compression of log")
+
+func ParseCompression(name string) (Compression, error) {
+	switch name {
+	case "none":
+		return NoCompression, nil
+	case "flate":
+		return Flate, nil
+	default:
+		return 0, fmt.Errorf("unknown compression: %q, it should be none or flate", name)
+	}
+}
+
+func (c Compression) valid() bool {
+	return c == NoCompression || c == Flate
+}
+
+// writers and readers of flate allocate big state, so they are reused between records
+var flateWriters = sync.Pool{New: func() any {
+	w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+	return w
+}}
+
+var flateReaders = sync.Pool{New: func() any {
+	return flate.NewReader(nil)
+}}
+
+func compress(c Compression, payload []byte) ([]byte, error) {
+	if c == NoCompression {
+		return payload, nil
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(payload)/2))
+
+	w := flateWriters.Get().(*flate.Writer)
+	defer flateWriters.Put(w)
+
+	w.Reset(out)
+
+	if _, err := w.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func decompress(c Compression, payload []byte) ([]byte, error) {
+	if c == NoCompression {
+		return payload, nil
+	}
+
+	r := flateReaders.Get().(io.ReadCloser)
+	defer flateReaders.Put(r)
+
+	if err := r.(flate.Resetter).Reset(bytes.NewReader(payload), nil); err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,75 @@
+package binaryEvent
+
+import (
+	"bufio"
+	"bytes"
+	"cache/core"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadCompressed(t *testing.T) {
+	flate := Encoding{Compression: Flate}
+	mockFile := bytes.NewBuffer(nil)
+
+	for i := range cases {
+		if err := flate.Write(mockFile, cases[i].event); err != nil {
+			t.Fatalf("case %q: %v", cases[i].name, err)
+		}
+	}
+
+	r := bufio.NewReader(mockFile)
+
+	for i := range cases {
+		got, err := flate.Read(r)
+		if err != nil {
+			t.Fatalf("case %q: %v", cases[i].name, err)
+		}
+		if !equalEvents(got, cases[i].event) {
+			t.Fatalf("case %q: got %v, want %v", cases[i].name, got, cases[i].event)
+		}
+	}
+
+	if _, err := flate.Read(r); !errors.Is(err, ErrEmptyFile) {
+		t.Fatalf("got error %v after last event, want %v", err, ErrEmptyFile)
+	}
+}
+
+func TestCompressedRecord(t *testing.T) {
+	event := core.Event{ID: 1, Type: core.EventPut, Key: "user:1", Value: []byte(strings.Repeat(`{"name":"user","active":true}`, 100))}
+
+	flate := Encoding{Compression: Flate}
+
+	plain, compressed := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if err := WriteTo(plain, event); err != nil {
+		t.Fatal(err)
+	}
+	if err := flate.Write(compressed, event); err != nil {
+		t.Fatal(err)
+	}
+
+	if compressed.Len()*10 > plain.Len() {
+		t.Fatalf("compressed record has %d bytes, plain one has %d", compressed.Len(), plain.Len())
+	}
+
+	//checksums are of compressed payload, so broken record is never decompressed
+	for i := 0; i < compressed.Len(); i++ {
+		broken := bytes.Clone(compressed.Bytes())
+		broken[i] ^= 0x10
+
+		if _, err := flate.Read(bytes.NewReader(broken)); !errors.Is(err, ErrChecksum) {
+			t.Fatalf("byte %d: got error %v, want %v", i, err, ErrChecksum)
+		}
+	}
+
+	for cut := 1; cut < compressed.Len(); cut++ {
+		if _, err := flate.Read(bytes.NewReader(compressed.Bytes()[:cut])); !errors.Is(err, ErrTruncated) {
+			t.Fatalf("cut at %d: got error %v, want %v", cut, err, ErrTruncated)
+		}
+	}
+
+	if _, err := ParseCompression("zstd"); err == nil {
+		t.Fatal("got no error of unknown compression")
+	}
+}
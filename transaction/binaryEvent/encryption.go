@@ -0,0 +1,172 @@
+package binaryEvent
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownKey = errors.New("key of the log is not found")
+var ErrDecrypt = errors.New("record could not be decrypted")
+var ErrKeyExhausted = errors.New("encrypted file reached the limit of its key")
+
+// maxKeyID is the biggest key ID, its length is one byte of the header
+const maxKeyID = 255
+
+// saltSize is size of random salt of encrypted file, key of the file is derived
+// from the key of the keyring and the salt, so every file has its own key
+const saltSize = 16
+
+// MaxEncryptedSize limits records of one encrypted file. Nonces are random, so one key
+// should not encrypt more than 2^32 records, every record takes at least
+// minEncryptedRecord bytes, so the file of this size has less than 2^32 of them
+const MaxEncryptedSize int64 = 1 << 37
+
+// minEncryptedRecord is length, checksums, nonce and tag of empty encrypted record
+const minEncryptedRecord = 1 + 4 + 12 + 16 + 4
+
+// Keyring keeps AES keys by their IDs, new files are encrypted by the active
+// key and the others decrypt files written before rotation
+type Keyring struct {
+	keys   map[string][]byte
+	active string
+}
+
+// ParseKeyring parses entries "id:key" separated by new lines or commas, key is
+// base64 of 16, 24 or 32 bytes for AES-128, AES-192 or AES-256. The last entry
+// is the active key, empty lines and lines starting with # are skipped
+func ParseKeyring(text string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte)}
+
+	for _, entry := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == ',' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, encoded, found := strings.Cut(entry, ":")
+		if !found || id == "" || len(id) > maxKeyID {
+			return nil, fmt.Errorf("invalid key entry, it should be id:base64 key with id up to %d bytes", maxKeyID)
+		}
+
+		if _, exists := k.keys[id]; exists {
+			return nil, fmt.Errorf("key %q is duplicated", id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+
+		if _, err = aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+
+		k.keys[id] = key
+		k.active = id
+	}
+
+	if k.active == "" {
+		return nil, errors.New("keyring has no keys")
+	}
+
+	return k, nil
+}
+
+// Active returns ID of the key new files are encrypted by, empty for nil keyring
+func (k *Keyring) Active() string {
+	if k == nil {
+		return ""
+	}
+
+	return k.active
+}
+
+// aead returns cipher of the file encrypted by the key with the salt,
+// nil for empty ID of file that is not encrypted
+func (k *Keyring) aead(id string, salt [saltSize]byte) (cipher.AEAD, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	var key []byte
+	if k != nil {
+		key = k.keys[id]
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	block, err := aes.NewCipher(deriveKey(key, salt))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// deriveKey is HKDF-SHA256 of the key with the salt, it has the length of the key,
+// so one block of the expansion is enough
+func deriveKey(key []byte, salt [saltSize]byte) []byte {
+	extract := hmac.New(sha256.New, salt[:])
+	extract.Write(key)
+
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte("cache log file key\x01"))
+
+	return expand.Sum(nil)[:len(key)]
+}
+
+// newSalt returns random salt of the new file
+func newSalt() ([saltSize]byte, error) {
+	var salt [saltSize]byte
+	_, err := rand.Read(salt[:])
+
+	return salt, err
+}
+
+// additionalData binds encrypted record to the header of its file
+// and to its offset after the header
+func additionalData(header []byte, offset int64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte(nil), header...), uint64(offset))
+}
+
+// encrypt returns random nonce followed by encrypted payload and its tag
+func encrypt(aead cipher.AEAD, payload []byte, data []byte) ([]byte, error) {
+	if aead == nil {
+		return payload, nil
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(payload)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, payload, data), nil
+}
+
+func decrypt(aead cipher.AEAD, payload []byte, data []byte) ([]byte, error) {
+	if aead == nil {
+		return payload, nil
+	}
+
+	if len(payload) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	nonce, sealed := payload[:aead.NonceSize()], payload[aead.NonceSize():]
+
+	plain, err := aead.Open(nil, nonce, sealed, data)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plain, nil
+}
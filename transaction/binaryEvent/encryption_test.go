@@ -0,0 +1,147 @@
+package binaryEvent
+
+import (
+	"bytes"
+	"cache/core"
+	"errors"
+	"testing"
+)
+
+// testKeys has two AES-256 keys, "new" is the active one
+const testKeys = `# rotated keys
+old:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=
+new:HxwdHhsaGRgXFhUUExIREA8ODQwLCgkIBwYFBAMCAQA=`
+
+func TestEncryptedRecord(t *testing.T) {
+	keys, err := ParseKeyring(testKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.Active() != "new" {
+		t.Fatalf("got active key %q, want %q", keys.Active(), "new")
+	}
+
+	event := core.Event{ID: 1, Type: core.EventPut, Key: "user:1", Value: []byte("secret value")}
+
+	for _, compression := range []Compression{NoCompression, Flate} {
+		header, err := Header{Version: Version, Compression: compression, KeyID: "old"}.Salted()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		enc, err := header.Encoding(keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		record := bytes.NewBuffer(nil)
+		if err = enc.Write(record, event); err != nil {
+			t.Fatal(err)
+		}
+
+		if bytes.Contains(record.Bytes(), event.Value) || bytes.Contains(record.Bytes(), []byte(event.Key)) {
+			t.Fatal("record has plain key or value")
+		}
+
+		read, err := header.Encoding(keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := read.Read(bytes.NewReader(record.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalEvents(got, event) {
+			t.Fatalf("got %v, want %v", got, event)
+		}
+
+		//checksums are right, but the key is not
+		wrongKey := header
+		wrongKey.KeyID = "new"
+
+		//another file with the same key has another salt
+		anotherFile, err := header.Salted()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, h := range []Header{wrongKey, anotherFile} {
+			wrong, err := h.Encoding(keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = wrong.Read(bytes.NewReader(record.Bytes())); !errors.Is(err, ErrDecrypt) {
+				t.Fatalf("got error %v, want %v", err, ErrDecrypt)
+			}
+		}
+
+		//record is bound to its offset in the file
+		moved, err := header.Encoding(keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moved.SetOffset(int64(record.Len()))
+
+		if _, err = moved.Read(bytes.NewReader(record.Bytes())); !errors.Is(err, ErrDecrypt) {
+			t.Fatalf("got error %v of moved record, want %v", err, ErrDecrypt)
+		}
+
+		//encrypted record is never read as plain one
+		if _, err = (Encoding{Compression: compression}).Read(bytes.NewReader(record.Bytes())); err == nil {
+			t.Fatal("got no error of encrypted record read without key")
+		}
+	}
+
+	if _, err = (Header{Version: Version, KeyID: "lost"}).Encoding(keys); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("got error %v, want %v", err, ErrUnknownKey)
+	}
+	if _, err = (Header{Version: Version, KeyID: "old"}).Encoding(nil); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("got error %v without keyring, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestKeyExhausted(t *testing.T) {
+	keys, err := ParseKeyring(testKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := Header{Version: Version, KeyID: "new"}.Encoding(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc.SetOffset(MaxEncryptedSize - minEncryptedRecord)
+
+	record := bytes.NewBuffer(nil)
+	if err = enc.Write(record, core.Event{ID: 1, Type: core.EventPut, Key: "key"}); !errors.Is(err, ErrKeyExhausted) {
+		t.Fatalf("got error %v, want %v", err, ErrKeyExhausted)
+	}
+	if record.Len() != 0 {
+		t.Fatalf("got %d bytes written over the limit", record.Len())
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	keys, err := ParseKeyring("a:AAECAwQFBgcICQoLDA0ODw==, b:AAECAwQFBgcICQoLDA0ODxAREhMUFRYX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.Active() != "b" {
+		t.Fatalf("got active key %q, want %q", keys.Active(), "b")
+	}
+
+	for _, text := range []string{
+		"",
+		"# no keys",
+		"AAECAwQFBgcICQoLDA0ODw==",
+		"a:not base64",
+		"a:AAECAwQF",
+		"a:AAECAwQFBgcICQoLDA0ODw==\na:AAECAwQFBgcICQoLDA0ODw==",
+	} {
+		if _, err = ParseKeyring(text); err == nil {
+			t.Fatalf("got no error of keyring %q", text)
+		}
+	}
+}
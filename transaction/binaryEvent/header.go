@@ -0,0 +1,194 @@
+package binaryEvent
+
+import (
+	"bufio"
+	"bytes"
+	"cache/core"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// magic starts every log file written since the header was introduced,
+// files without it are of LegacyVersion
+const magic = "CLOG"
+
+const (
+	// LegacyVersion is the first format, it has no header and no checksums,
+	// IDs of it are renumbered by migration
+	LegacyVersion byte = 0
+	// Version is the format written by WriteHeader: header with format, compression,
+	// key ID and salt of records, checksums of records and uvarint numbers. Versions
+	// between them were written only by development builds, they are not read
+	Version byte = 5
+)
+
+// HeaderSize is size of magic, version, format, compression and length of key ID
+// of the current version, key ID and salt of encrypted file follow them
+const HeaderSize = len(magic) + 4
+
+var ErrUnsupportedVersion = errors.New("unsupported version of log format")
+
+// Header tells how records of the file are encoded
+type Header struct {
+	Version byte
+	//Format of records, zero is the format of Encoding, others are written by users
+	//of the package with WriteRecord, Compression and KeyID are applied to them as well
+	Format      byte
+	Compression Compression
+	//KeyID of the key records are encrypted by, empty if they are not encrypted
+	KeyID string
+	//Salt derives the key of the file from the key of KeyID, it is set by Salted
+	Salt [saltSize]byte
+}
+
+// Size of the header in the file
+func (h Header) Size() int64 {
+	if h.Version == LegacyVersion {
+		return 0
+	}
+
+	return int64(len(h.bytes()))
+}
+
+// Salted returns header of the new file, encrypted file gets random salt,
+// so its records are encrypted by its own key
+func (h Header) Salted() (Header, error) {
+	if h.KeyID == "" {
+		return h, nil
+	}
+
+	var err error
+	h.Salt, err = newSalt()
+
+	return h, err
+}
+
+// bytes returns header of the current version as it is written
+func (h Header) bytes() []byte {
+	header := append([]byte(magic), Version, h.Format, byte(h.Compression), byte(len(h.KeyID)))
+	header = append(header, h.KeyID...)
+
+	if h.KeyID != "" {
+		header = append(header, h.Salt[:]...)
+	}
+
+	return header
+}
+
+// Encoding of records of one file, it is made from its header. Encoding of encrypted
+// file counts offset of its records, so one Encoding either reads the file from
+// the start or writes to it from the offset set by SetOffset
+type Encoding struct {
+	Compression Compression
+	//aead encrypts records, nil if they are not encrypted
+	aead cipher.AEAD
+	//header and offset of the record after it are additional data of encrypted
+	//record, so it could not be moved to another file or place
+	header []byte
+	offset *int64
+}
+
+// Encoding returns encoding of records of the file, keys could be nil if
+// the file is not encrypted
+func (h Header) Encoding(keys *Keyring) (Encoding, error) {
+	aead, err := keys.aead(h.KeyID, h.Salt)
+	if err != nil {
+		return Encoding{}, err
+	}
+
+	return Encoding{Compression: h.Compression, aead: aead, header: h.bytes(), offset: new(int64)}, nil
+}
+
+// Seek sets offset of the next record after the header, records are appended
+// to the file from its end
+func (enc Encoding) SetOffset(offset int64) {
+	if enc.offset != nil {
+		*enc.offset = offset
+	}
+}
+
+// ReadFunc reads one event, it returns ErrEmptyFile when reader is finished
+type ReadFunc func(r io.Reader) (core.Event, error)
+
+// ReaderOf returns function those reads events of the file with the header
+func ReaderOf(h Header, keys *Keyring) (ReadFunc, error) {
+	switch h.Version {
+	case LegacyVersion:
+		return readLegacy, nil
+	case Version:
+		if h.Format != 0 {
+			return nil, fmt.Errorf("records of format %d are not events of Encoding", h.Format)
+		}
+
+		enc, err := h.Encoding(keys)
+		if err != nil {
+			return nil, err
+		}
+
+		return enc.Read, nil
+	default:
+		return nil, fmt.Errorf("%w: %d, supported %d", ErrUnsupportedVersion, h.Version, Version)
+	}
+}
+
+// WriteHeader writes header of the current version with format, compression, key ID and salt of h
+func WriteHeader(w io.Writer, h Header) error {
+	if len(h.KeyID) > maxKeyID {
+		return fmt.Errorf("key ID is longer than %d bytes", maxKeyID)
+	}
+
+	_, err := w.Write(h.bytes())
+	return err
+}
+
+// ReadHeader reads header of the file, nothing is read if there is no header,
+// that is LegacyVersion. ErrTruncated means that reader ended inside the header
+// and ErrNotLegacy that file without header has checksummed records
+func ReadHeader(buf *bufio.Reader) (Header, error) {
+	header, err := buf.Peek(len(magic) + 1)
+	if len(header) == 0 {
+		return Header{}, ErrEmptyFile
+	}
+
+	if err != nil && bytes.HasPrefix([]byte(magic), header) {
+		return Header{}, fmt.Errorf("read header was failed: %w", ErrTruncated)
+	}
+
+	if err != nil || string(header[:len(magic)]) != magic {
+		//the only other files without header
+		if checksummed(buf) {
+			return Header{}, ErrNotLegacy
+		}
+
+		return Header{Version: LegacyVersion}, nil
+	}
+
+	h := Header{Version: header[len(magic)]}
+	if h.Version != Version {
+		return Header{}, fmt.Errorf("%w: %d, supported %d", ErrUnsupportedVersion, h.Version, Version)
+	}
+
+	if header, err = buf.Peek(HeaderSize); err != nil {
+		return Header{}, fmt.Errorf("read header was failed: %w", ErrTruncated)
+	}
+
+	h.Format, h.Compression = header[len(magic)+1], Compression(header[len(magic)+2])
+	if !h.Compression.valid() {
+		return Header{}, fmt.Errorf("%w: %d", ErrUnsupportedCompression, h.Compression)
+	}
+
+	if keyID := int(header[len(magic)+3]); keyID > 0 {
+		//key ID of encrypted file is followed by its salt
+		if header, err = buf.Peek(HeaderSize + keyID + saltSize); err != nil {
+			return Header{}, fmt.Errorf("read header was failed: %w", ErrTruncated)
+		}
+
+		h.KeyID = string(header[HeaderSize : HeaderSize+keyID])
+		copy(h.Salt[:], header[HeaderSize+keyID:])
+	}
+
+	_, err = buf.Discard(int(h.Size()))
+	return h, err
+}
@@ -0,0 +1,111 @@
+package binaryEvent
+
+import (
+	"bufio"
+	"bytes"
+	"cache/core"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// legacyLog is written by the first version: put of "key" with ID 1
+// and delete of it with ID 2, numbers end with zero byte
+var legacyLog = []byte("\x01\x00\x01\x03\x00key\x05\x00value" + "\x02\x00\x00\x03\x00key\x00")
+
+func TestReadHeader(t *testing.T) {
+	header := bytes.NewBuffer(nil)
+	if err := WriteHeader(header, Header{Format: 2, Compression: Flate}); err != nil {
+		t.Fatal(err)
+	}
+
+	salt := [saltSize]byte{1, 2, 3}
+
+	encrypted := bytes.NewBuffer(nil)
+	if err := WriteHeader(encrypted, Header{Compression: Flate, KeyID: "2024-01", Salt: salt}); err != nil {
+		t.Fatal(err)
+	}
+
+	checksummed := bytes.NewBuffer(nil)
+	if err := WriteTo(checksummed, core.Event{ID: 1, Type: core.EventPut, Key: "key"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		header Header
+		err    error
+	}{
+		{"current", header.Bytes(), Header{Version, 2, Flate, "", [saltSize]byte{}}, nil},
+		{"encrypted", encrypted.Bytes(), Header{Version, 0, Flate, "2024-01", salt}, nil},
+		{"empty", nil, Header{}, ErrEmptyFile},
+		{"torn", header.Bytes()[:2], Header{}, ErrTruncated},
+		{"torn compression", header.Bytes()[:HeaderSize-2], Header{}, ErrTruncated},
+		{"torn key ID", encrypted.Bytes()[:HeaderSize+2], Header{}, ErrTruncated},
+		{"torn salt", encrypted.Bytes()[:encrypted.Len()-1], Header{}, ErrTruncated},
+		{"legacy", legacyLog, Header{LegacyVersion, 0, NoCompression, "", [saltSize]byte{}}, nil},
+		{"checksummed without header", checksummed.Bytes(), Header{}, ErrNotLegacy},
+		{"newer", []byte(magic + "\x7f"), Header{}, ErrUnsupportedVersion},
+		{"development", []byte(magic + "\x01"), Header{}, ErrUnsupportedVersion},
+		{"unknown compression", []byte(magic + "\x05\x00\x7f\x00"), Header{}, ErrUnsupportedCompression},
+	}
+
+	for _, test := range tests {
+		r := bufio.NewReader(bytes.NewReader(test.data))
+
+		h, err := ReadHeader(r)
+		if h != test.header || !errors.Is(err, test.err) {
+			t.Fatalf("%s: got header %+v (%v), want %+v (%v)", test.name, h, err, test.header, test.err)
+		}
+
+		if read := len(test.data) - r.Buffered(); err == nil && int64(read) != h.Size() {
+			t.Fatalf("%s: read %d bytes of header, its size is %d", test.name, read, h.Size())
+		}
+	}
+}
+
+func TestReadLegacy(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader(legacyLog))
+
+	h, err := ReadHeader(r)
+	if err != nil || h.Version != LegacyVersion {
+		t.Fatalf("got version %d (%v), want %d", h.Version, err, LegacyVersion)
+	}
+
+	read, err := ReaderOf(h, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []core.Event{
+		{ID: 1, Type: core.EventPut, Key: "key", Value: []byte("value")},
+		{ID: 2, Type: core.EventDelete, Key: "key"},
+	}
+
+	for _, w := range want {
+		got, err := read(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Fatalf("got %v, want %v", got, w)
+		}
+	}
+
+	if _, err = read(r); !errors.Is(err, ErrEmptyFile) {
+		t.Fatalf("got error %v after last event, want %v", err, ErrEmptyFile)
+	}
+
+	if _, err = read(bytes.NewReader(legacyLog[:5])); !errors.Is(err, ErrTruncated) {
+		t.Fatalf("got error %v of torn event, want %v", err, ErrTruncated)
+	}
+
+	//uvarint numbers have no zero bytes and types of later builds are unknown
+	notLegacy := [][]byte{[]byte("\x01\x01\x03key\x05value"), []byte("\x01\x00\x03\x03\x00key\x00")}
+	for _, data := range notLegacy {
+		if _, err = read(bytes.NewReader(data)); !errors.Is(err, ErrNotLegacy) {
+			t.Fatalf("got error %v of %q, want %v", err, data, ErrNotLegacy)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package binaryEvent
+
+import (
+	"bufio"
+	"cache/core"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// ErrNotLegacy means that file has no header, but it was not written by the first version,
+// builds between it and the header wrote such files, their events could not be read reliably
+var ErrNotLegacy = errors.New("file without header is not a log of the first version")
+
+// checksummed tells if the file starts with length of record and its checksum,
+// builds right before the header wrote records of Encoding without it
+func checksummed(buf *bufio.Reader) bool {
+	head, _ := buf.Peek(binary.MaxVarintLen64 + 4)
+
+	_, n := binary.Uvarint(head)
+	if n <= 0 || len(head) < n+4 {
+		return false
+	}
+
+	return binary.LittleEndian.Uint32(head[n:]) == crc32.Checksum(head[:n], castagnoli)
+}
+
+// readLegacyNum reads number of LegacyVersion, it was written as little endian
+// bytes up to the first zero one and read as their sum, so only numbers below
+// 256 survived, number is read the same way to get what the old reader got.
+// The first version always wrote the zero byte, number without it is not legacy
+func readLegacyNum(buf *bufio.Reader) (uint64, error) {
+	var n uint64
+
+	for i := 0; i < 8; i++ {
+		b, err := buf.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b == 0 {
+			return n, nil
+		}
+
+		n += uint64(b)
+	}
+
+	return 0, ErrNotLegacy
+}
+
+func readLegacyString(buf *bufio.Reader) (string, error) {
+	length, err := readLegacyNum(buf)
+	if err != nil {
+		return "", err
+	}
+
+	str := make([]byte, length)
+	if _, err := io.ReadFull(buf, str); err != nil {
+		return "", err
+	}
+
+	return string(str), nil
+}
+
+// readLegacy reads event of LegacyVersion: ID, type, key and value
+func readLegacy(r io.Reader) (e core.Event, err error) {
+	tmp := "read %s of legacy event was failed: %w"
+	buf := bufio.NewReader(r)
+
+	if _, err = buf.Peek(1); err != nil {
+		return e, ErrEmptyFile
+	}
+
+	//there are no checksums, so any short read is torn record
+	truncated := func(field string, err error) error {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = ErrTruncated
+		}
+
+		return fmt.Errorf(tmp, field, err)
+	}
+
+	if e.ID, err = readLegacyNum(buf); err != nil {
+		return e, truncated("id", err)
+	}
+
+	if e.Type, err = buf.ReadByte(); err != nil {
+		return e, truncated("type", err)
+	}
+
+	//the first version had only these types
+	if e.Type != core.EventDelete && e.Type != core.EventPut && e.Type != core.EventClear {
+		return e, fmt.Errorf(tmp, "type", ErrNotLegacy)
+	}
+
+	if e.Key, err = readLegacyString(buf); err != nil {
+		return e, truncated("key", err)
+	}
+
+	value, err := readLegacyString(buf)
+	if err != nil {
+		return e, truncated("value", err)
+	}
+
+	if value != "" && e.Type != core.EventPut {
+		return e, fmt.Errorf(tmp, "value", ErrNotLegacy)
+	}
+
+	if value != "" {
+		e.Value = []byte(value)
+	}
+
+	return e, nil
+}